@@ -1,5 +1,16 @@
 package cc_messages
 
+import (
+	"regexp"
+	"strconv"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+const AppCrashedReason = "CRASHED"
+
+var exitStatusPattern = regexp.MustCompile(`[Ee]xited with status (-?\d+)`)
+
 type AppCrashedRequest struct {
 	Instance        string `json:"instance"`
 	Index           int    `json:"index"`
@@ -10,3 +21,33 @@ type AppCrashedRequest struct {
 	CrashCount      int    `json:"crash_count"`
 	CrashTimestamp  int64  `json:"crash_timestamp"`
 }
+
+func NewAppCrashedRequest(event *models.ActualLRPCrashedEvent) AppCrashedRequest {
+	exitStatus, _ := ParseExitStatus(event.CrashReason)
+
+	return AppCrashedRequest{
+		Instance:        event.InstanceGuid,
+		Index:           int(event.Index),
+		CellID:          event.CellId,
+		Reason:          AppCrashedReason,
+		ExitStatus:      exitStatus,
+		ExitDescription: event.CrashReason,
+		CrashCount:      int(event.CrashCount),
+		CrashTimestamp:  event.Since,
+	}
+}
+
+// ParseExitStatus extracts the process exit status from a crash reason such
+// as "APP/PROC/WEB: Exited with status 137 (out of memory)".
+func ParseExitStatus(crashReason string) (int, bool) {
+	match := exitStatusPattern.FindStringSubmatch(crashReason)
+	if match == nil {
+		return 0, false
+	}
+
+	status, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return status, true
+}
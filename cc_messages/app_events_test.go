@@ -0,0 +1,156 @@
+package cc_messages_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("App events", func() {
+	lrpKey := models.NewActualLRPKey("process-guid", 2, "domain")
+	instanceKey := models.NewActualLRPInstanceKey("instance-guid", "cell-id")
+	netInfo := models.NewActualLRPNetInfo("1.2.3.4", "2.2.2.2", models.ActualLRPNetInfo_PreferredAddressUnknown, models.NewPortMapping(61000, 8080))
+
+	running := func(mutate ...func(*models.ActualLRP)) *models.ActualLRP {
+		lrp := &models.ActualLRP{
+			ActualLRPKey:         lrpKey,
+			ActualLRPInstanceKey: instanceKey,
+			ActualLRPNetInfo:     netInfo,
+			State:                models.ActualLRPStateRunning,
+			Since:                1257894000,
+			Presence:             models.ActualLRP_Ordinary,
+		}
+		for _, m := range mutate {
+			m(lrp)
+		}
+		return lrp
+	}
+
+	Describe("NewAppCrashedRequest", func() {
+		DescribeTable("translates the crash event",
+			func(crashReason string, expectedExitStatus int) {
+				after := &models.ActualLRP{
+					ActualLRPKey: lrpKey,
+					State:        models.ActualLRPStateCrashed,
+					CrashCount:   3,
+					CrashReason:  crashReason,
+					Since:        1257894100,
+				}
+				event := models.NewActualLRPCrashedEvent(running(), after)
+
+				Expect(cc_messages.NewAppCrashedRequest(event)).To(Equal(cc_messages.AppCrashedRequest{
+					Instance:        "instance-guid",
+					Index:           2,
+					CellID:          "cell-id",
+					Reason:          "CRASHED",
+					ExitStatus:      expectedExitStatus,
+					ExitDescription: crashReason,
+					CrashCount:      3,
+					CrashTimestamp:  1257894100,
+				}))
+			},
+			Entry("exited with status", "APP/PROC/WEB: Exited with status 1", 1),
+			Entry("out of memory", "APP/PROC/WEB: Exited with status 137 (out of memory)", 137),
+			Entry("lowercase", "exited with status 2", 2),
+			Entry("failed health check", "Instance never healthy after 1m0s: Failed to make TCP connection to port 8080: connection refused", 0),
+			Entry("no reason", "", 0),
+		)
+	})
+
+	Describe("ParseExitStatus", func() {
+		It("reports whether a status was found", func() {
+			status, ok := cc_messages.ParseExitStatus("APP/PROC/WEB: Exited with status 143")
+			Expect(ok).To(BeTrue())
+			Expect(status).To(Equal(143))
+
+			_, ok = cc_messages.ParseExitStatus("Copying into the container failed")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("NewAppReschedulingRequest", func() {
+		DescribeTable("detects rescheduled instances",
+			func(before, after *models.ActualLRP, expected cc_messages.AppReschedulingRequest, expectedOK bool) {
+				request, ok := cc_messages.NewAppReschedulingRequest(models.NewActualLRPInstanceChangedEvent(before, after, "trace-id"))
+				Expect(ok).To(Equal(expectedOK))
+				Expect(request).To(Equal(expected))
+			},
+			Entry("evacuating",
+				running(),
+				running(func(lrp *models.ActualLRP) { lrp.Presence = models.ActualLRP_Evacuating }),
+				cc_messages.AppReschedulingRequest{Instance: "instance-guid", Index: 2, CellID: "cell-id", Reason: cc_messages.AppReschedulingEvacuatingReason},
+				true,
+			),
+			Entry("lost",
+				running(),
+				&models.ActualLRP{ActualLRPKey: lrpKey, State: models.ActualLRPStateUnclaimed},
+				cc_messages.AppReschedulingRequest{Instance: "instance-guid", Index: 2, CellID: "cell-id", Reason: cc_messages.AppReschedulingLostReason},
+				true,
+			),
+			Entry("lost without room to place it",
+				running(),
+				&models.ActualLRP{ActualLRPKey: lrpKey, State: models.ActualLRPStateUnclaimed, PlacementError: "insufficient resources: memory"},
+				cc_messages.AppReschedulingRequest{Instance: "instance-guid", Index: 2, CellID: "cell-id", Reason: "insufficient resources: memory"},
+				true,
+			),
+			Entry("still running",
+				running(),
+				running(func(lrp *models.ActualLRP) { lrp.Since++ }),
+				cc_messages.AppReschedulingRequest{},
+				false,
+			),
+			Entry("starting up",
+				&models.ActualLRP{ActualLRPKey: lrpKey, State: models.ActualLRPStateUnclaimed},
+				&models.ActualLRP{ActualLRPKey: lrpKey, ActualLRPInstanceKey: instanceKey, State: models.ActualLRPStateClaimed},
+				cc_messages.AppReschedulingRequest{},
+				false,
+			),
+		)
+	})
+
+	Describe("NewAppReadinessChangedRequest", func() {
+		routable := func(routable bool) func(*models.ActualLRP) {
+			return func(lrp *models.ActualLRP) { lrp.SetRoutable(routable) }
+		}
+
+		DescribeTable("detects readiness transitions",
+			func(before, after *models.ActualLRP, expected cc_messages.AppReadinessChangedRequest, expectedOK bool) {
+				request, ok := cc_messages.NewAppReadinessChangedRequest(models.NewActualLRPInstanceChangedEvent(before, after, "trace-id"))
+				Expect(ok).To(Equal(expectedOK))
+				Expect(request).To(Equal(expected))
+			},
+			Entry("becomes ready",
+				running(routable(false)),
+				running(routable(true)),
+				cc_messages.AppReadinessChangedRequest{Instance: "instance-guid", Index: 2, CellID: "cell-id", Ready: true},
+				true,
+			),
+			Entry("becomes unready",
+				running(routable(true)),
+				running(routable(false)),
+				cc_messages.AppReadinessChangedRequest{Instance: "instance-guid", Index: 2, CellID: "cell-id", Ready: false},
+				true,
+			),
+			Entry("unchanged",
+				running(routable(true)),
+				running(routable(true)),
+				cc_messages.AppReadinessChangedRequest{},
+				false,
+			),
+			Entry("no readiness reported",
+				running(),
+				running(),
+				cc_messages.AppReadinessChangedRequest{},
+				false,
+			),
+			Entry("stopped running",
+				running(routable(true)),
+				running(routable(false), func(lrp *models.ActualLRP) { lrp.State = models.ActualLRPStateCrashed }),
+				cc_messages.AppReadinessChangedRequest{},
+				false,
+			),
+		)
+	})
+})
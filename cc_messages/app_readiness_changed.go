@@ -1,8 +1,29 @@
 package cc_messages
 
+import "code.cloudfoundry.org/bbs/models"
+
 type AppReadinessChangedRequest struct {
 	Instance string `json:"instance"`
 	Index    int    `json:"index"`
 	CellID   string `json:"cell_id"`
 	Ready    bool   `json:"ready"`
 }
+
+// NewAppReadinessChangedRequest reports whether the event changes the
+// routability of a running instance.
+func NewAppReadinessChangedRequest(event *models.ActualLRPInstanceChangedEvent) (AppReadinessChangedRequest, bool) {
+	if event.Before == nil || event.After == nil || event.After.State != models.ActualLRPStateRunning {
+		return AppReadinessChangedRequest{}, false
+	}
+
+	if !event.After.RoutableExists() || (event.Before.RoutableExists() && event.Before.GetRoutable() == event.After.GetRoutable()) {
+		return AppReadinessChangedRequest{}, false
+	}
+
+	return AppReadinessChangedRequest{
+		Instance: event.InstanceGuid,
+		Index:    int(event.Index),
+		CellID:   event.CellId,
+		Ready:    event.After.GetRoutable(),
+	}, true
+}
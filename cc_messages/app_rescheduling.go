@@ -1,8 +1,44 @@
 package cc_messages
 
+import "code.cloudfoundry.org/bbs/models"
+
+const (
+	AppReschedulingEvacuatingReason = "Cell is being evacuated"
+	AppReschedulingLostReason       = "Instance was lost and is being rescheduled"
+)
+
 type AppReschedulingRequest struct {
 	Instance string `json:"instance"`
 	Index    int    `json:"index"`
 	CellID   string `json:"cell_id"`
 	Reason   string `json:"reason,omitempty"`
 }
+
+// NewAppReschedulingRequest reports whether the event moves a running
+// instance elsewhere, either because its cell is evacuating or because the
+// instance went back to being unclaimed.
+func NewAppReschedulingRequest(event *models.ActualLRPInstanceChangedEvent) (AppReschedulingRequest, bool) {
+	if event.Before == nil || event.After == nil || event.Before.State != models.ActualLRPStateRunning {
+		return AppReschedulingRequest{}, false
+	}
+
+	var reason string
+	switch {
+	case event.Before.Presence != models.ActualLRP_Evacuating && event.After.Presence == models.ActualLRP_Evacuating:
+		reason = AppReschedulingEvacuatingReason
+	case event.After.State == models.ActualLRPStateUnclaimed:
+		reason = AppReschedulingLostReason
+		if event.After.PlacementError != "" {
+			reason = event.After.PlacementError
+		}
+	default:
+		return AppReschedulingRequest{}, false
+	}
+
+	return AppReschedulingRequest{
+		Instance: event.InstanceGuid,
+		Index:    int(event.Index),
+		CellID:   event.CellId,
+		Reason:   reason,
+	}, true
+}
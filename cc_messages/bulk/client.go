@@ -0,0 +1,153 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+)
+
+const (
+	DefaultPageSize      = 500
+	DefaultRetryInterval = time.Second
+)
+
+var ErrInvalidToken = errors.New("invalid bulk token")
+
+type ErrUnexpectedStatus struct {
+	StatusCode int
+	Body       string
+}
+
+func (e ErrUnexpectedStatus) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+type Config struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	PageSize   int
+	MaxRetries int
+	// RetryInterval is the pause between attempts at fetching the same page.
+	RetryInterval time.Duration
+	HTTPClient    *http.Client
+}
+
+type Client struct {
+	config Config
+}
+
+func NewClient(config Config) *Client {
+	if config.PageSize <= 0 {
+		config.PageSize = DefaultPageSize
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRetryInterval
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &Client{config: config}
+}
+
+func InitialToken() *json.RawMessage {
+	token := json.RawMessage(`{}`)
+	return &token
+}
+
+func EncodeToken(token cc_messages.CCBulkToken) (*json.RawMessage, error) {
+	encoded, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(encoded)
+	return &raw, nil
+}
+
+func DecodeToken(raw *json.RawMessage) (cc_messages.CCBulkToken, error) {
+	token := cc_messages.CCBulkToken{}
+	if raw == nil {
+		return token, ErrInvalidToken
+	}
+
+	err := json.Unmarshal(*raw, &token)
+	if err != nil {
+		return token, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	return token, nil
+}
+
+// fetch decodes each attempt into a fresh response, so a body that failed to
+// decode partway leaves nothing behind for the retry.
+func fetch[R any](ctx context.Context, c *Client, path string, params map[string]string, token *json.RawMessage) (R, error) {
+	var err error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				var zero R
+				return zero, ctx.Err()
+			case <-time.After(c.config.RetryInterval):
+			}
+		}
+
+		var response R
+		err = c.fetchOnce(ctx, path, params, token, &response)
+		if err == nil || !retryable(ctx, err) {
+			return response, err
+		}
+	}
+
+	var zero R
+	return zero, err
+}
+
+func (c *Client) fetchOnce(ctx context.Context, path string, params map[string]string, token *json.RawMessage, response interface{}) error {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set("batch_size", strconv.Itoa(c.config.PageSize))
+	query.Set("token", string(*token))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return ErrUnexpectedStatus{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr ErrUnexpectedStatus
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package bulk
+
+import (
+	"context"
+	"sort"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+)
+
+// DesiredStateDiff is what a bulk sync has to do to bring BBS in line with
+// CC. Create and Update hold the fingerprints of apps to fetch in full from
+// CC; Delete holds the process guids of LRPs CC no longer desires.
+type DesiredStateDiff struct {
+	Create []cc_messages.CCDesiredAppFingerprint
+	Update []cc_messages.CCDesiredAppFingerprint
+	Delete []string
+}
+
+func (d DesiredStateDiff) Empty() bool {
+	return len(d.Create) == 0 && len(d.Update) == 0 && len(d.Delete) == 0
+}
+
+// Differ compares CC fingerprints against the LRPs in BBS a page at a time.
+// It keeps only the process guid and etag of each LRP, and the guids of the
+// fingerprints seen so far, so the full desired state is never in memory.
+type Differ struct {
+	existing map[string]string
+	seen     map[string]struct{}
+}
+
+// NewDiffer indexes the scheduling infos by process guid. LRPs outside the
+// CC app domain are ignored so they are never deleted. The etag of an LRP is
+// its annotation, as set by the DesiredLRPBuilder.
+func NewDiffer(schedulingInfos []*models.DesiredLRPSchedulingInfo) *Differ {
+	existing := make(map[string]string, len(schedulingInfos))
+	for _, schedulingInfo := range schedulingInfos {
+		if schedulingInfo.Domain != cc_messages.AppLRPDomain {
+			continue
+		}
+		existing[schedulingInfo.ProcessGuid] = schedulingInfo.Annotation
+	}
+
+	return &Differ{
+		existing: existing,
+		seen:     map[string]struct{}{},
+	}
+}
+
+// Page returns the creates and updates for one page of fingerprints. A
+// process guid repeated on a later page is ignored.
+func (d *Differ) Page(fingerprints []cc_messages.CCDesiredAppFingerprint) DesiredStateDiff {
+	diff := DesiredStateDiff{}
+	for _, fingerprint := range fingerprints {
+		if _, ok := d.seen[fingerprint.ProcessGuid]; ok {
+			continue
+		}
+		d.seen[fingerprint.ProcessGuid] = struct{}{}
+
+		etag, ok := d.existing[fingerprint.ProcessGuid]
+		switch {
+		case !ok:
+			diff.Create = append(diff.Create, fingerprint)
+		case etag != fingerprint.ETag:
+			diff.Update = append(diff.Update, fingerprint)
+		}
+	}
+	return diff
+}
+
+// Deletes returns the sorted process guids of LRPs that no page contained.
+// It is only meaningful once every page has been consumed.
+func (d *Differ) Deletes() []string {
+	deletes := []string{}
+	for processGuid := range d.existing {
+		if _, ok := d.seen[processGuid]; !ok {
+			deletes = append(deletes, processGuid)
+		}
+	}
+	sort.Strings(deletes)
+	return deletes
+}
+
+// DiffFingerprints streams the fingerprint pages of it through a Differ,
+// calling handle with the creates and updates of each page and finally with
+// the deletes. Deletes are only reported when every page was fetched, since
+// an interrupted sync cannot tell a missing app from an unfetched one.
+func DiffFingerprints(
+	ctx context.Context,
+	it *Iterator[cc_messages.CCDesiredStateFingerprintResponse, cc_messages.CCDesiredAppFingerprint],
+	schedulingInfos []*models.DesiredLRPSchedulingInfo,
+	handle func(DesiredStateDiff) error,
+) error {
+	differ := NewDiffer(schedulingInfos)
+
+	for it.Next(ctx) {
+		diff := differ.Page(it.Page())
+		if diff.Empty() {
+			continue
+		}
+
+		err := handle(diff)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	deletes := differ.Deletes()
+	if len(deletes) == 0 {
+		return nil
+	}
+	return handle(DesiredStateDiff{Delete: deletes})
+}
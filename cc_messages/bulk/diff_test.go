@@ -0,0 +1,143 @@
+package bulk_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/bulk"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func schedulingInfo(processGuid, etag string) *models.DesiredLRPSchedulingInfo {
+	return &models.DesiredLRPSchedulingInfo{
+		DesiredLRPKey: models.NewDesiredLRPKey(processGuid, cc_messages.AppLRPDomain, "log-guid"),
+		Annotation:    etag,
+	}
+}
+
+var _ = Describe("Diff", func() {
+	Describe("Differ", func() {
+		var differ *bulk.Differ
+
+		BeforeEach(func() {
+			otherDomain := schedulingInfo("other", "")
+			otherDomain.Domain = "other-domain"
+
+			differ = bulk.NewDiffer([]*models.DesiredLRPSchedulingInfo{
+				schedulingInfo("a", "a-etag"),
+				schedulingInfo("b", "stale-etag"),
+				schedulingInfo("c", "c-etag"),
+				schedulingInfo("d", "d-etag"),
+				otherDomain,
+			})
+		})
+
+		It("classifies fingerprints across pages", func() {
+			Expect(differ.Page(fingerprints("a", "b"))).To(Equal(bulk.DesiredStateDiff{
+				Update: fingerprints("b"),
+			}))
+			Expect(differ.Page(fingerprints("e", "c"))).To(Equal(bulk.DesiredStateDiff{
+				Create: fingerprints("e"),
+			}))
+			Expect(differ.Deletes()).To(Equal([]string{"d"}))
+		})
+
+		It("ignores a process guid repeated on a later page", func() {
+			Expect(differ.Page(fingerprints("e")).Create).To(HaveLen(1))
+			Expect(differ.Page(fingerprints("e")).Empty()).To(BeTrue())
+		})
+	})
+
+	Describe("DiffFingerprints", func() {
+		var (
+			server *ghttp.Server
+			client *bulk.Client
+			infos  []*models.DesiredLRPSchedulingInfo
+			diffs  []bulk.DesiredStateDiff
+			handle func(bulk.DesiredStateDiff) error
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			client = bulk.NewClient(bulk.Config{
+				BaseURL:       server.URL(),
+				PageSize:      2,
+				RetryInterval: time.Millisecond,
+			})
+			infos = []*models.DesiredLRPSchedulingInfo{
+				schedulingInfo("a", "a-etag"),
+				schedulingInfo("b", "stale-etag"),
+				schedulingInfo("z", "z-etag"),
+			}
+			diffs = nil
+			handle = func(diff bulk.DesiredStateDiff) error {
+				diffs = append(diffs, diff)
+				return nil
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("reports each page and then the deletes", func() {
+			server.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+					Fingerprints: fingerprints("a", "b"),
+					CCBulkToken:  token(2),
+				}),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+					Fingerprints: fingerprints("c"),
+					CCBulkToken:  token(3),
+				}),
+			)
+
+			err := bulk.DiffFingerprints(context.Background(), bulk.NewIterator(client, bulk.Fingerprints), infos, handle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(Equal([]bulk.DesiredStateDiff{
+				{Update: fingerprints("b")},
+				{Create: fingerprints("c")},
+				{Delete: []string{"z"}},
+			}))
+		})
+
+		It("reports no deletes when a page fails", func() {
+			server.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+					Fingerprints: fingerprints("c", "d"),
+					CCBulkToken:  token(2),
+				}),
+				ghttp.RespondWith(http.StatusUnauthorized, ""),
+			)
+
+			err := bulk.DiffFingerprints(context.Background(), bulk.NewIterator(client, bulk.Fingerprints), infos, handle)
+			Expect(err).To(MatchError(bulk.ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized}))
+			Expect(diffs).To(Equal([]bulk.DesiredStateDiff{
+				{Create: fingerprints("c", "d")},
+			}))
+		})
+
+		It("stops when the handler fails", func() {
+			server.AppendHandlers(
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+					Fingerprints: fingerprints("c", "d"),
+					CCBulkToken:  token(2),
+				}),
+			)
+
+			handlerErr := errors.New("bbs unavailable")
+			err := bulk.DiffFingerprints(context.Background(), bulk.NewIterator(client, bulk.Fingerprints), infos, func(bulk.DesiredStateDiff) error {
+				return handlerErr
+			})
+			Expect(err).To(Equal(handlerErr))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+})
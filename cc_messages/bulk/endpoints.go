@@ -0,0 +1,35 @@
+package bulk
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+)
+
+type Endpoint[R any, T any] struct {
+	Path   string
+	Params map[string]string
+	Items  func(R) []T
+	Token  func(R) *json.RawMessage
+}
+
+var DesiredApps = Endpoint[cc_messages.CCDesiredStateServerResponse, cc_messages.DesireAppRequestFromCC]{
+	Path:  "/internal/bulk/apps",
+	Items: func(r cc_messages.CCDesiredStateServerResponse) []cc_messages.DesireAppRequestFromCC { return r.Apps },
+	Token: func(r cc_messages.CCDesiredStateServerResponse) *json.RawMessage { return r.CCBulkToken },
+}
+
+var Fingerprints = Endpoint[cc_messages.CCDesiredStateFingerprintResponse, cc_messages.CCDesiredAppFingerprint]{
+	Path:   "/internal/bulk/apps",
+	Params: map[string]string{"format": "fingerprint"},
+	Items: func(r cc_messages.CCDesiredStateFingerprintResponse) []cc_messages.CCDesiredAppFingerprint {
+		return r.Fingerprints
+	},
+	Token: func(r cc_messages.CCDesiredStateFingerprintResponse) *json.RawMessage { return r.CCBulkToken },
+}
+
+var TaskStates = Endpoint[cc_messages.CCTaskStatesResponse, cc_messages.CCTaskState]{
+	Path:  "/internal/v3/bulk/task_states",
+	Items: func(r cc_messages.CCTaskStatesResponse) []cc_messages.CCTaskState { return r.TaskStates },
+	Token: func(r cc_messages.CCTaskStatesResponse) *json.RawMessage { return r.CCBulkToken },
+}
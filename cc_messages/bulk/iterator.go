@@ -0,0 +1,104 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Iterator walks the pages of a bulk endpoint, feeding the token of each
+// response into the request for the next one. It stops after a page that is
+// empty, shorter than the page size, or carries no token.
+//
+//	iterator := bulk.NewIterator(client, bulk.Fingerprints)
+//	for iterator.Next(ctx) {
+//		process(iterator.Page())
+//	}
+//	if err := iterator.Err(); err != nil { ... }
+type Iterator[R any, T any] struct {
+	client   *Client
+	endpoint Endpoint[R, T]
+
+	token *json.RawMessage
+	page  []T
+	done  bool
+	err   error
+}
+
+func NewIterator[R any, T any](client *Client, endpoint Endpoint[R, T]) *Iterator[R, T] {
+	return &Iterator[R, T]{
+		client:   client,
+		endpoint: endpoint,
+		token:    InitialToken(),
+	}
+}
+
+// Resume continues iteration from a token previously returned by Token. A nil
+// token starts from the beginning.
+func (it *Iterator[R, T]) Resume(token *json.RawMessage) *Iterator[R, T] {
+	if token == nil {
+		token = InitialToken()
+	}
+	it.token = token
+	return it
+}
+
+func (it *Iterator[R, T]) Next(ctx context.Context) bool {
+	it.page = nil
+	if it.done || it.err != nil {
+		return false
+	}
+
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	response, err := fetch[R](ctx, it.client, it.endpoint.Path, it.endpoint.Params, it.token)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	page := it.endpoint.Items(response)
+	token := it.endpoint.Token(response)
+	if token != nil {
+		if _, err := DecodeToken(token); err != nil {
+			it.err = err
+			return false
+		}
+		it.token = token
+	}
+
+	if token == nil || len(page) < it.client.config.PageSize {
+		it.done = true
+	}
+
+	if len(page) == 0 {
+		return false
+	}
+
+	it.page = page
+	return true
+}
+
+func (it *Iterator[R, T]) Page() []T {
+	return it.page
+}
+
+// Token is the token to request the page after the current one.
+func (it *Iterator[R, T]) Token() *json.RawMessage {
+	return it.token
+}
+
+func (it *Iterator[R, T]) Err() error {
+	return it.err
+}
+
+// All drains the iterator into a single slice.
+func All[R any, T any](ctx context.Context, it *Iterator[R, T]) ([]T, error) {
+	items := []T{}
+	for it.Next(ctx) {
+		items = append(items, it.Page()...)
+	}
+	return items, it.Err()
+}
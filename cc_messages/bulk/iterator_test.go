@@ -0,0 +1,209 @@
+package bulk_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/bulk"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func token(id int) *json.RawMessage {
+	raw, err := bulk.EncodeToken(cc_messages.CCBulkToken{Id: id})
+	Expect(err).NotTo(HaveOccurred())
+	return raw
+}
+
+func fingerprints(guids ...string) []cc_messages.CCDesiredAppFingerprint {
+	fingerprints := []cc_messages.CCDesiredAppFingerprint{}
+	for _, guid := range guids {
+		fingerprints = append(fingerprints, cc_messages.CCDesiredAppFingerprint{ProcessGuid: guid, ETag: guid + "-etag"})
+	}
+	return fingerprints
+}
+
+var _ = Describe("Iterator", func() {
+	var (
+		server *ghttp.Server
+		client *bulk.Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		client = bulk.NewClient(bulk.Config{
+			BaseURL:       server.URL(),
+			Username:      "internal_user",
+			Password:      "internal_password",
+			PageSize:      2,
+			MaxRetries:    2,
+			RetryInterval: time.Millisecond,
+		})
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("follows the tokens until a short page", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/internal/bulk/apps", "batch_size=2&format=fingerprint&token={}"),
+				ghttp.VerifyBasicAuth("internal_user", "internal_password"),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+					Fingerprints: fingerprints("a", "b"),
+					CCBulkToken:  token(2),
+				}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/internal/bulk/apps", `batch_size=2&format=fingerprint&token={"id":2}`),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+					Fingerprints: fingerprints("c"),
+					CCBulkToken:  token(3),
+				}),
+			),
+		)
+
+		iterator := bulk.NewIterator(client, bulk.Fingerprints)
+		Expect(iterator.Next(ctx)).To(BeTrue())
+		Expect(iterator.Page()).To(Equal(fingerprints("a", "b")))
+		Expect(iterator.Token()).To(Equal(token(2)))
+
+		Expect(iterator.Next(ctx)).To(BeTrue())
+		Expect(iterator.Page()).To(Equal(fingerprints("c")))
+
+		Expect(iterator.Next(ctx)).To(BeFalse())
+		Expect(iterator.Err()).NotTo(HaveOccurred())
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+
+	It("stops on an empty page", func() {
+		server.AppendHandlers(
+			ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCTaskStatesResponse{
+				TaskStates:  []cc_messages.CCTaskState{{TaskGuid: "a"}, {TaskGuid: "b"}},
+				CCBulkToken: token(2),
+			}),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/internal/v3/bulk/task_states", `batch_size=2&token={"id":2}`),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCTaskStatesResponse{
+					TaskStates:  []cc_messages.CCTaskState{},
+					CCBulkToken: token(2),
+				}),
+			),
+		)
+
+		states, err := bulk.All(ctx, bulk.NewIterator(client, bulk.TaskStates))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(states).To(Equal([]cc_messages.CCTaskState{{TaskGuid: "a"}, {TaskGuid: "b"}}))
+	})
+
+	It("resumes from a previous token", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/internal/bulk/apps", `batch_size=2&token={"id":7}`),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateServerResponse{
+					Apps: []cc_messages.DesireAppRequestFromCC{{ProcessGuid: "g"}},
+				}),
+			),
+		)
+
+		apps, err := bulk.All(ctx, bulk.NewIterator(client, bulk.DesiredApps).Resume(token(7)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps).To(HaveLen(1))
+	})
+
+	It("starts from the beginning when resumed from a nil token", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/internal/bulk/apps", "batch_size=2&token={}"),
+				ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateServerResponse{}),
+			),
+		)
+
+		apps, err := bulk.All(ctx, bulk.NewIterator(client, bulk.DesiredApps).Resume(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps).To(BeEmpty())
+	})
+
+	It("discards a partly decoded response before retrying", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusOK, `{"token":{"id":9},"fingerprints":[{"process_guid":1}]}`),
+			ghttp.RespondWith(http.StatusOK, `{"fingerprints":[{"process_guid":"a","etag":"a-etag"},{"process_guid":"b","etag":"b-etag"}]}`),
+		)
+
+		iterator := bulk.NewIterator(client, bulk.Fingerprints)
+		Expect(iterator.Next(ctx)).To(BeTrue())
+		Expect(iterator.Page()).To(Equal(fingerprints("a", "b")))
+		Expect(iterator.Next(ctx)).To(BeFalse())
+		Expect(iterator.Err()).NotTo(HaveOccurred())
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+
+	It("retries server errors", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusServiceUnavailable, "try again"),
+			ghttp.RespondWith(http.StatusBadGateway, "try again"),
+			ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+				Fingerprints: fingerprints("a"),
+			}),
+		)
+
+		page, err := bulk.All(ctx, bulk.NewIterator(client, bulk.Fingerprints))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(page).To(Equal(fingerprints("a")))
+	})
+
+	It("gives up after the configured retries", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusInternalServerError, "boom"),
+			ghttp.RespondWith(http.StatusInternalServerError, "boom"),
+			ghttp.RespondWith(http.StatusInternalServerError, "boom"),
+		)
+
+		iterator := bulk.NewIterator(client, bulk.Fingerprints)
+		Expect(iterator.Next(ctx)).To(BeFalse())
+		Expect(iterator.Err()).To(Equal(bulk.ErrUnexpectedStatus{StatusCode: http.StatusInternalServerError, Body: "boom"}))
+		Expect(server.ReceivedRequests()).To(HaveLen(3))
+	})
+
+	It("does not retry client errors", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, ""))
+
+		iterator := bulk.NewIterator(client, bulk.Fingerprints)
+		Expect(iterator.Next(ctx)).To(BeFalse())
+		Expect(iterator.Err()).To(MatchError(bulk.ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized}))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("fails on a malformed token", func() {
+		malformed := json.RawMessage(`"not-a-token"`)
+		server.AppendHandlers(
+			ghttp.RespondWithJSONEncoded(http.StatusOK, cc_messages.CCDesiredStateFingerprintResponse{
+				Fingerprints: fingerprints("a", "b"),
+				CCBulkToken:  &malformed,
+			}),
+		)
+
+		iterator := bulk.NewIterator(client, bulk.Fingerprints)
+		Expect(iterator.Next(ctx)).To(BeFalse())
+		Expect(errors.Is(iterator.Err(), bulk.ErrInvalidToken)).To(BeTrue())
+	})
+
+	It("stops when the context is cancelled", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		iterator := bulk.NewIterator(client, bulk.Fingerprints)
+		Expect(iterator.Next(cancelled)).To(BeFalse())
+		Expect(iterator.Err()).To(MatchError(context.Canceled))
+		Expect(server.ReceivedRequests()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,199 @@
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	DefaultMaxRetries     = 3
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultTimeout        = 30 * time.Second
+)
+
+var ErrBaseURLInvalid = errors.New("invalid base url")
+
+type ClientError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e ClientError) Error() string {
+	return fmt.Sprintf("cloud controller rejected request with status %d: %s", e.StatusCode, e.Body)
+}
+
+type ServerError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e ServerError) Error() string {
+	return fmt.Sprintf("cloud controller failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+type Config struct {
+	BaseURL string
+	// TLSConfig carries the CA to trust and, for mutual TLS, the client
+	// certificate.
+	TLSConfig *tls.Config
+	Username  string
+	Password  string
+
+	// MaxRetries is the number of attempts after the first one; a negative
+	// value disables retries.
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+}
+
+type Client struct {
+	baseURL    string
+	config     Config
+	httpClient *http.Client
+}
+
+func NewClient(config Config) (*Client, error) {
+	baseURL, err := url.Parse(config.BaseURL)
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrBaseURLInvalid, config.BaseURL)
+	}
+
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLSConfig
+
+	return &Client{
+		baseURL: baseURL.String(),
+		config:  config,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+	}, nil
+}
+
+func (c *Client) AppCrashed(ctx context.Context, processGuid string, request cc_messages.AppCrashedRequest) error {
+	key := idempotencyKey("crashed", request.Instance, request.Index, strconv.Itoa(request.CrashCount))
+	return c.post(ctx, processGuid, "crashed", key, request)
+}
+
+// AppRescheduling takes the modification tag of the actual LRP that is being
+// moved, as the same instance can be rescheduled more than once.
+func (c *Client) AppRescheduling(ctx context.Context, processGuid string, tag models.ModificationTag, request cc_messages.AppReschedulingRequest) error {
+	key := idempotencyKey("rescheduling", request.Instance, request.Index, fmt.Sprintf("%s:%d", tag.Epoch, tag.Index))
+	return c.post(ctx, processGuid, "rescheduling", key, request)
+}
+
+// AppReadinessChanged takes the modification tag of the actual LRP after the
+// change, as an instance can return to the same readiness more than once.
+func (c *Client) AppReadinessChanged(ctx context.Context, processGuid string, tag models.ModificationTag, request cc_messages.AppReadinessChangedRequest) error {
+	key := idempotencyKey("readiness_changed", request.Instance, request.Index, strconv.FormatBool(request.Ready), fmt.Sprintf("%s:%d", tag.Epoch, tag.Index))
+	return c.post(ctx, processGuid, "readiness_changed", key, request)
+}
+
+// idempotencyKey identifies an event for an instance so that a retried
+// delivery is recognised as the same event.
+func idempotencyKey(event, instance string, index int, discriminators ...string) string {
+	key := fmt.Sprintf("%s/%d/%s", instance, index, event)
+	for _, d := range discriminators {
+		key += "/" + d
+	}
+	return key
+}
+
+func (c *Client) post(ctx context.Context, processGuid, event, key string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.baseURL + "/internal/v4/apps/" + url.PathEscape(processGuid) + "/" + event
+
+	backoff := c.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err = c.postOnce(ctx, endpoint, key, body)
+		if err == nil || !retryable(ctx, err) || attempt >= c.config.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.config.MaxBackoff {
+			backoff = c.config.MaxBackoff
+		}
+	}
+}
+
+func (c *Client) postOnce(ctx context.Context, endpoint, key string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode >= 500 {
+		return ServerError{StatusCode: resp.StatusCode, Body: string(responseBody)}
+	}
+	return ClientError{StatusCode: resp.StatusCode, Body: string(responseBody)}
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var clientErr ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
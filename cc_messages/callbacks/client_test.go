@@ -0,0 +1,194 @@
+package callbacks_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/callbacks"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/fakecc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		ctx    context.Context
+		config callbacks.Config
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		config = callbacks.Config{
+			Username:       "internal_user",
+			Password:       "internal_password",
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     2 * time.Millisecond,
+		}
+	})
+
+	It("rejects an invalid base url", func() {
+		config.BaseURL = "cc.service.cf.internal"
+		_, err := callbacks.NewClient(config)
+		Expect(errors.Is(err, callbacks.ErrBaseURLInvalid)).To(BeTrue())
+	})
+
+	Context("against the cloud controller", func() {
+		var (
+			server *fakecc.Server
+			client *callbacks.Client
+		)
+
+		BeforeEach(func() {
+			server = fakecc.New()
+			server.SetCredentials("internal_user", "internal_password")
+			config.BaseURL = server.URL
+
+			var err error
+			client, err = callbacks.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("delivers each event", func() {
+			crashed := cc_messages.AppCrashedRequest{Instance: "instance-guid", Index: 1, Reason: "CRASHED", ExitStatus: 137, CrashCount: 2}
+			rescheduling := cc_messages.AppReschedulingRequest{Instance: "instance-guid", Index: 1, Reason: "evacuating"}
+			readiness := cc_messages.AppReadinessChangedRequest{Instance: "instance-guid", Index: 1, Ready: true}
+
+			Expect(client.AppCrashed(ctx, "process-guid", crashed)).To(Succeed())
+			Expect(client.AppRescheduling(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 1}, rescheduling)).To(Succeed())
+			Expect(client.AppReadinessChanged(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 1}, readiness)).To(Succeed())
+
+			Expect(server.AppCrashed()).To(Equal([]fakecc.AppCrashed{{ProcessGuid: "process-guid", Request: crashed}}))
+			Expect(server.AppRescheduling()).To(Equal([]fakecc.AppRescheduling{{ProcessGuid: "process-guid", Request: rescheduling}}))
+			Expect(server.AppReadinessChanged()).To(Equal([]fakecc.AppReadinessChanged{{ProcessGuid: "process-guid", Request: readiness}}))
+		})
+
+		It("retries server errors", func() {
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusServiceUnavailable, 2)
+
+			Expect(client.AppCrashed(ctx, "process-guid", cc_messages.AppCrashedRequest{})).To(Succeed())
+			Expect(server.RequestCount(fakecc.AppCrashedRoute)).To(Equal(3))
+			Expect(server.AppCrashed()).To(HaveLen(1))
+		})
+
+		It("returns a server error once retries are exhausted", func() {
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusBadGateway, 3)
+
+			err := client.AppCrashed(ctx, "process-guid", cc_messages.AppCrashedRequest{})
+			var serverErr callbacks.ServerError
+			Expect(errors.As(err, &serverErr)).To(BeTrue())
+			Expect(serverErr.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(server.RequestCount(fakecc.AppCrashedRoute)).To(Equal(3))
+		})
+
+		It("does not retry client errors", func() {
+			server.FailNext(fakecc.AppReadinessChangedRoute, http.StatusNotFound, 1)
+
+			err := client.AppReadinessChanged(ctx, "process-guid", models.ModificationTag{}, cc_messages.AppReadinessChangedRequest{})
+			Expect(err).To(Equal(callbacks.ClientError{StatusCode: http.StatusNotFound}))
+			Expect(server.RequestCount(fakecc.AppReadinessChangedRoute)).To(Equal(1))
+		})
+
+		It("stops retrying when the context is done", func() {
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusServiceUnavailable, 10)
+			config.InitialBackoff = time.Hour
+			client, err := callbacks.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			Expect(client.AppCrashed(ctx, "process-guid", cc_messages.AppCrashedRequest{})).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+
+	Context("request details", func() {
+		var server *ghttp.Server
+
+		BeforeEach(func() {
+			server = ghttp.NewTLSServer()
+
+			pool := x509.NewCertPool()
+			pool.AddCert(server.HTTPTestServer.Certificate())
+			config.TLSConfig = &tls.Config{RootCAs: pool}
+			config.BaseURL = server.URL()
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("sends the same idempotency key on every attempt for an event", func() {
+			key := "instance-guid/3/crashed/5"
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/internal/v4/apps/process-guid/crashed"),
+					ghttp.VerifyBasicAuth("internal_user", "internal_password"),
+					ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, key),
+					ghttp.VerifyJSONRepresenting(cc_messages.AppCrashedRequest{Instance: "instance-guid", Index: 3, CrashCount: 5}),
+					ghttp.RespondWith(http.StatusInternalServerError, ""),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, key),
+					ghttp.RespondWith(http.StatusAccepted, ""),
+				),
+			)
+
+			client, err := callbacks.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.AppCrashed(ctx, "process-guid", cc_messages.AppCrashedRequest{Instance: "instance-guid", Index: 3, CrashCount: 5})).To(Succeed())
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("distinguishes repeated readiness transitions", func() {
+			server.AppendHandlers(
+				ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, "instance-guid/0/readiness_changed/true/epoch:1"),
+				ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, "instance-guid/0/readiness_changed/false/epoch:2"),
+				ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, "instance-guid/0/readiness_changed/true/epoch:3"),
+			)
+
+			client, err := callbacks.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+			ready := cc_messages.AppReadinessChangedRequest{Instance: "instance-guid", Ready: true}
+			unready := cc_messages.AppReadinessChangedRequest{Instance: "instance-guid", Ready: false}
+			Expect(client.AppReadinessChanged(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 1}, ready)).To(Succeed())
+			Expect(client.AppReadinessChanged(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 2}, unready)).To(Succeed())
+			Expect(client.AppReadinessChanged(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 3}, ready)).To(Succeed())
+		})
+
+		It("distinguishes repeated reschedules of an instance", func() {
+			server.AppendHandlers(
+				ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, "instance-guid/0/rescheduling/epoch:1"),
+				ghttp.VerifyHeaderKV(callbacks.IdempotencyKeyHeader, "instance-guid/0/rescheduling/epoch:4"),
+			)
+
+			client, err := callbacks.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+			evacuating := cc_messages.AppReschedulingRequest{Instance: "instance-guid", Reason: cc_messages.AppReschedulingEvacuatingReason}
+			lost := cc_messages.AppReschedulingRequest{Instance: "instance-guid", Reason: cc_messages.AppReschedulingLostReason}
+			Expect(client.AppRescheduling(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 1}, evacuating)).To(Succeed())
+			Expect(client.AppRescheduling(ctx, "process-guid", models.ModificationTag{Epoch: "epoch", Index: 4}, lost)).To(Succeed())
+		})
+
+		It("fails when the server certificate is not trusted", func() {
+			config.TLSConfig = &tls.Config{}
+			config.MaxRetries = -1
+			client, err := callbacks.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.AppRescheduling(ctx, "process-guid", models.ModificationTag{}, cc_messages.AppReschedulingRequest{})).To(MatchError(ContainSubstring("certificate")))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+})
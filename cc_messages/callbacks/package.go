@@ -0,0 +1 @@
+package callbacks // import "code.cloudfoundry.org/runtimeschema/cc_messages/callbacks"
@@ -0,0 +1,105 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type ErrMalformedRoutes struct {
+	Key string
+	Err error
+}
+
+func (err ErrMalformedRoutes) Error() string {
+	return fmt.Sprintf("malformed %s in routing info: %s", err.Key, err.Err)
+}
+
+func (err ErrMalformedRoutes) Unwrap() error {
+	return err.Err
+}
+
+func NewCCRouteInfo(httpRoutes CCHTTPRoutes, tcpRoutes CCTCPRoutes) (CCRouteInfo, error) {
+	return CCRouteInfo{}.WithRoutes(httpRoutes, tcpRoutes)
+}
+
+func (info CCRouteInfo) HTTPRoutes() (CCHTTPRoutes, error) {
+	var routes CCHTTPRoutes
+	err := info.decode(CC_HTTP_ROUTES, &routes)
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+func (info CCRouteInfo) TCPRoutes() (CCTCPRoutes, error) {
+	var routes CCTCPRoutes
+	err := info.decode(CC_TCP_ROUTES, &routes)
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// UnknownRoutes returns the entries other than http_routes and tcp_routes, so
+// that callers rewriting the known routes can carry the rest over unchanged.
+func (info CCRouteInfo) UnknownRoutes() CCRouteInfo {
+	unknown := CCRouteInfo{}
+	for key, payload := range info {
+		if key != CC_HTTP_ROUTES && key != CC_TCP_ROUTES {
+			unknown[key] = payload
+		}
+	}
+	return unknown
+}
+
+// WithRoutes returns a copy of the routing info with the given http and tcp
+// routes. A nil slice leaves the existing entry for that key in place, and
+// keys this package does not know about are always kept untouched.
+func (info CCRouteInfo) WithRoutes(httpRoutes CCHTTPRoutes, tcpRoutes CCTCPRoutes) (CCRouteInfo, error) {
+	merged := make(CCRouteInfo, len(info)+2)
+	for key, payload := range info {
+		merged[key] = payload
+	}
+
+	if httpRoutes != nil {
+		payload, err := encodeRoutes(httpRoutes)
+		if err != nil {
+			return nil, err
+		}
+		merged[CC_HTTP_ROUTES] = payload
+	}
+
+	if tcpRoutes != nil {
+		payload, err := encodeRoutes(tcpRoutes)
+		if err != nil {
+			return nil, err
+		}
+		merged[CC_TCP_ROUTES] = payload
+	}
+
+	return merged, nil
+}
+
+func (info CCRouteInfo) decode(key string, routes interface{}) error {
+	payload, ok := info[key]
+	if !ok || payload == nil {
+		return nil
+	}
+
+	err := json.Unmarshal(*payload, routes)
+	if err != nil {
+		return ErrMalformedRoutes{Key: key, Err: err}
+	}
+
+	return nil
+}
+
+func encodeRoutes(routes interface{}) (*json.RawMessage, error) {
+	routesJson, err := json.Marshal(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	routesPayload := json.RawMessage(routesJson)
+	return &routesPayload, nil
+}
@@ -0,0 +1,22 @@
+package compat
+
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Check fails t for every breaking change between the snapshot stored at path
+// and the current message types.
+func Check(t TestingT, path string) {
+	t.Helper()
+
+	old, err := Load(path)
+	if err != nil {
+		t.Errorf("failed to load wire snapshot %s: %s", path, err)
+		return
+	}
+
+	for _, change := range Compare(old, Current()) {
+		t.Errorf("breaking wire change: %s", change)
+	}
+}
@@ -0,0 +1,252 @@
+package compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/jsonschema"
+)
+
+type Field struct {
+	GoName    string `json:"go_name"`
+	JSONName  string `json:"json_name"`
+	Type      string `json:"type"`
+	OmitEmpty bool   `json:"omitempty"`
+}
+
+// Snapshot records the wire shape of every struct reachable from the
+// messages, keyed by package-qualified type name.
+type Snapshot map[string][]Field
+
+type ChangeKind string
+
+const (
+	TypeRemoved      ChangeKind = "type removed"
+	FieldRemoved     ChangeKind = "field removed"
+	FieldRenamed     ChangeKind = "field renamed"
+	FieldTypeChanged ChangeKind = "field type changed"
+	OmitEmptyChanged ChangeKind = "omitempty changed"
+)
+
+type Change struct {
+	Kind  ChangeKind
+	Type  string
+	Field string
+	Old   string
+	New   string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case TypeRemoved:
+		return fmt.Sprintf("%s: %s", c.Type, c.Kind)
+	case FieldRemoved:
+		return fmt.Sprintf("%s.%s: %s", c.Type, c.Field, c.Kind)
+	default:
+		return fmt.Sprintf("%s.%s: %s from %s to %s", c.Type, c.Field, c.Kind, c.Old, c.New)
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func Take(messages []jsonschema.Message) Snapshot {
+	snapshot := Snapshot{}
+	for _, message := range messages {
+		snapshot.add(reflect.TypeOf(message.Value))
+	}
+	return snapshot
+}
+
+func Current() Snapshot {
+	return Take(jsonschema.Messages)
+}
+
+func Load(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := Snapshot{}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s Snapshot) Write(path string) error {
+	encoded, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(encoded, '\n'), 0644)
+}
+
+// Compare lists the changes from old to current that a peer still speaking
+// old cannot tolerate. Added types and fields are compatible and are not
+// reported.
+func Compare(old, current Snapshot) []Change {
+	changes := []Change{}
+	for _, typeName := range sortedKeys(old) {
+		currentFields, ok := current[typeName]
+		if !ok {
+			changes = append(changes, Change{Kind: TypeRemoved, Type: typeName})
+			continue
+		}
+
+		for _, oldField := range old[typeName] {
+			changes = append(changes, compareField(typeName, oldField, currentFields)...)
+		}
+	}
+	return changes
+}
+
+func compareField(typeName string, old Field, currentFields []Field) []Change {
+	current, ok := findField(currentFields, func(f Field) bool { return f.JSONName == old.JSONName })
+	if !ok {
+		renamed, ok := findField(currentFields, func(f Field) bool { return f.GoName == old.GoName })
+		if !ok {
+			return []Change{{Kind: FieldRemoved, Type: typeName, Field: old.JSONName}}
+		}
+		return []Change{{Kind: FieldRenamed, Type: typeName, Field: old.JSONName, Old: old.JSONName, New: renamed.JSONName}}
+	}
+
+	changes := []Change{}
+	if current.Type != old.Type {
+		changes = append(changes, Change{Kind: FieldTypeChanged, Type: typeName, Field: old.JSONName, Old: old.Type, New: current.Type})
+	}
+	if current.OmitEmpty != old.OmitEmpty {
+		changes = append(changes, Change{
+			Kind:  OmitEmptyChanged,
+			Type:  typeName,
+			Field: old.JSONName,
+			Old:   fmt.Sprint(old.OmitEmpty),
+			New:   fmt.Sprint(current.OmitEmpty),
+		})
+	}
+	return changes
+}
+
+func findField(fields []Field, match func(Field) bool) (Field, bool) {
+	for _, f := range fields {
+		if match(f) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func (s Snapshot) add(t reflect.Type) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType || t.Implements(marshalerType) {
+		return
+	}
+
+	name := t.String()
+	if _, ok := s[name]; ok {
+		return
+	}
+
+	// registered before walking the fields so recursive types terminate
+	s[name] = []Field{}
+	s[name] = s.fields(t)
+}
+
+func (s Snapshot) fields(t reflect.Type) []Field {
+	fields := []Field{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, s.fields(embedded)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, Field{
+			GoName:    field.Name,
+			JSONName:  name,
+			Type:      wireType(field.Type),
+			OmitEmpty: hasOption(options, "omitempty"),
+		})
+		s.add(field.Type)
+	}
+	return fields
+}
+
+// wireType describes a type by its JSON encoding, so that named types over
+// the same kind (a string enum replacing a string) are not reported.
+func wireType(t reflect.Type) string {
+	if t == rawJSONType {
+		return "json.RawMessage"
+	}
+	if t == timeType || (t.Kind() != reflect.Ptr && t.Implements(marshalerType)) {
+		return t.String()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + wireType(t.Elem())
+	case reflect.Slice:
+		return "[]" + wireType(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), wireType(t.Elem()))
+	case reflect.Map:
+		return "map[" + t.Key().Kind().String() + "]" + wireType(t.Elem())
+	case reflect.Struct:
+		return t.String()
+	case reflect.Interface:
+		return "interface{}"
+	default:
+		return t.Kind().String()
+	}
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(s Snapshot) []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
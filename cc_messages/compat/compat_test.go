@@ -0,0 +1,131 @@
+package compat_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/compat"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/jsonschema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+type crashedV1 struct {
+	Instance    string `json:"instance"`
+	Index       int    `json:"index"`
+	DockerEmail string `json:"docker_email"`
+	Reason      string `json:"reason,omitempty"`
+	CellID      string `json:"cell_id"`
+}
+
+type crashedReason string
+
+type crashedV3 struct {
+	Instance    string        `json:"instance"`
+	Index       int           `json:"index"`
+	DockerEmail string        `json:"docker_email"`
+	Reason      crashedReason `json:"reason,omitempty"`
+	CellID      string        `json:"cell_id"`
+}
+
+type bulkResponse struct {
+	Token *json.RawMessage `json:"token"`
+}
+
+type crashedV2 struct {
+	Instance string `json:"instance"`
+	Index    uint   `json:"index"`
+	Reason   string `json:"reason"`
+	CellID   string `json:"cell"`
+	Details  string `json:"details"`
+}
+
+var _ = Describe("Compat", func() {
+	It("is wire compatible with the stored snapshot", func() {
+		compat.Check(GinkgoT(), "snapshot.json")
+	})
+
+	Describe("Compare", func() {
+		var (
+			old, current compat.Snapshot
+		)
+
+		BeforeEach(func() {
+			old = compat.Take([]jsonschema.Message{{Name: "Crashed", Value: crashedV1{}}})
+			v2 := compat.Take([]jsonschema.Message{{Name: "Crashed", Value: crashedV2{}}})
+			current = compat.Snapshot{"compat_test.crashedV1": v2["compat_test.crashedV2"]}
+		})
+
+		It("reports nothing for identical snapshots", func() {
+			Expect(compat.Compare(old, old)).To(BeEmpty())
+		})
+
+		It("reports removed fields, type changes, tag renames and omitempty changes", func() {
+			Expect(compat.Compare(old, current)).To(ConsistOf(
+				compat.Change{Kind: compat.FieldTypeChanged, Type: "compat_test.crashedV1", Field: "index", Old: "int", New: "uint"},
+				compat.Change{Kind: compat.FieldRemoved, Type: "compat_test.crashedV1", Field: "docker_email"},
+				compat.Change{Kind: compat.OmitEmptyChanged, Type: "compat_test.crashedV1", Field: "reason", Old: "true", New: "false"},
+				compat.Change{Kind: compat.FieldRenamed, Type: "compat_test.crashedV1", Field: "cell_id", Old: "cell_id", New: "cell"},
+			))
+		})
+
+		It("describes fields by their JSON encoding", func() {
+			v3 := compat.Take([]jsonschema.Message{{Name: "Crashed", Value: crashedV3{}}})
+			current = compat.Snapshot{"compat_test.crashedV1": v3["compat_test.crashedV3"]}
+			Expect(compat.Compare(old, current)).To(BeEmpty())
+
+			current["compat_test.crashedV1"][1].Type = "uint"
+			Expect(compat.Compare(old, current)).To(ConsistOf(
+				compat.Change{Kind: compat.FieldTypeChanged, Type: "compat_test.crashedV1", Field: "index", Old: "int", New: "uint"},
+			))
+		})
+
+		It("describes raw JSON independently of the toolchain", func() {
+			snapshot := compat.Take([]jsonschema.Message{{Name: "BulkResponse", Value: bulkResponse{}}})
+			Expect(snapshot["compat_test.bulkResponse"]).To(ConsistOf(
+				compat.Field{GoName: "Token", JSONName: "token", Type: "*json.RawMessage"},
+			))
+		})
+
+		It("reports removed types", func() {
+			Expect(compat.Compare(old, compat.Snapshot{})).To(ConsistOf(
+				compat.Change{Kind: compat.TypeRemoved, Type: "compat_test.crashedV1"},
+			))
+		})
+
+		It("tolerates added fields and types", func() {
+			old["compat_test.crashedV1"] = old["compat_test.crashedV1"][:2]
+			current["compat_test.crashedV1"] = append(old["compat_test.crashedV1"], compat.Field{GoName: "Details", JSONName: "details", Type: "string"})
+			current["compat_test.other"] = []compat.Field{}
+
+			Expect(compat.Compare(old, current)).To(BeEmpty())
+		})
+	})
+
+	Describe("Check", func() {
+		It("fails the test for every breaking change", func() {
+			t := &fakeT{}
+			compat.Check(t, "fixtures/does-not-exist.json")
+			Expect(t.errors).To(HaveLen(1))
+			Expect(t.errors[0]).To(ContainSubstring("failed to load wire snapshot"))
+		})
+	})
+
+	Describe("Change", func() {
+		It("describes the change", func() {
+			change := compat.Change{Kind: compat.FieldTypeChanged, Type: "cc_messages.AppCrashedRequest", Field: "index", Old: "int", New: "uint"}
+			Expect(change.String()).To(Equal("cc_messages.AppCrashedRequest.index: field type changed from int to uint"))
+		})
+	})
+})
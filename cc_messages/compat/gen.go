@@ -0,0 +1,17 @@
+//go:build ignore
+
+package main
+
+import (
+	"log"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/compat"
+)
+
+// Refreshes the stored snapshot once a breaking change is intended to ship.
+func main() {
+	err := compat.Current().Write("snapshot.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
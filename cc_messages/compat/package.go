@@ -0,0 +1 @@
+package compat // import "code.cloudfoundry.org/runtimeschema/cc_messages/compat"
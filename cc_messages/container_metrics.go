@@ -0,0 +1,113 @@
+package cc_messages
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	ContainerMetricCPU                 = "cpu"
+	ContainerMetricMemory              = "memory"
+	ContainerMetricDisk                = "disk"
+	ContainerMetricMemoryQuota         = "memory_quota"
+	ContainerMetricDiskQuota           = "disk_quota"
+	ContainerMetricCPUEntitlement      = "cpu_entitlement"
+	ContainerMetricAbsoluteUsage       = "absolute_usage"
+	ContainerMetricAbsoluteEntitlement = "absolute_entitlement"
+	ContainerMetricLogRate             = "log_rate"
+	ContainerMetricLogRateLimit        = "log_rate_limit"
+	ContainerMetricContainerAge        = "container_age"
+	ContainerMetricRxBytes             = "rx_bytes"
+	ContainerMetricTxBytes             = "tx_bytes"
+)
+
+// ContainerMetricEnvelope is the part of a loggregator v2 gauge envelope
+// emitted for an app container that the stats are built from.
+type ContainerMetricEnvelope struct {
+	Timestamp  int64
+	InstanceId string
+	Gauges     map[string]float64
+}
+
+// NewLRPInstanceStats merges the envelopes reported for one instance. A
+// gauge appearing in several envelopes takes its most recent value, and Time
+// is the timestamp of the most recent envelope. CPU values are converted from
+// percentages to fractions.
+func NewLRPInstanceStats(envelopes ...ContainerMetricEnvelope) *LRPInstanceStats {
+	if len(envelopes) == 0 {
+		return nil
+	}
+
+	latest := map[string]float64{}
+	latestTimestamp := map[string]int64{}
+	var timestamp int64
+	for _, envelope := range envelopes {
+		if envelope.Timestamp > timestamp {
+			timestamp = envelope.Timestamp
+		}
+		for name, value := range envelope.Gauges {
+			if t, ok := latestTimestamp[name]; ok && t > envelope.Timestamp {
+				continue
+			}
+			latest[name] = value
+			latestTimestamp[name] = envelope.Timestamp
+		}
+	}
+
+	stats := &LRPInstanceStats{
+		Time:          time.Unix(0, timestamp),
+		CpuPercentage: latest[ContainerMetricCPU] / 100,
+		MemoryBytes:   uint64(latest[ContainerMetricMemory]),
+		DiskBytes:     uint64(latest[ContainerMetricDisk]),
+
+		MemoryQuotaBytes:            optionalUint64(latest, ContainerMetricMemoryQuota),
+		DiskQuotaBytes:              optionalUint64(latest, ContainerMetricDiskQuota),
+		AbsoluteCpuUsageNanos:       optionalUint64(latest, ContainerMetricAbsoluteUsage),
+		AbsoluteCpuEntitlementNanos: optionalUint64(latest, ContainerMetricAbsoluteEntitlement),
+		LogRateBytesPerSecond:       optionalUint64(latest, ContainerMetricLogRate),
+		ContainerAgeNanos:           optionalUint64(latest, ContainerMetricContainerAge),
+		NetworkRxBytes:              optionalUint64(latest, ContainerMetricRxBytes),
+		NetworkTxBytes:              optionalUint64(latest, ContainerMetricTxBytes),
+	}
+
+	if value, ok := latest[ContainerMetricCPUEntitlement]; ok {
+		entitlement := value / 100
+		stats.CpuEntitlementPercentage = &entitlement
+	}
+	if value, ok := latest[ContainerMetricLogRateLimit]; ok {
+		limit := int64(value)
+		stats.LogRateLimitBytesPerSecond = &limit
+	}
+
+	return stats
+}
+
+// LRPInstanceStatsByIndex groups the envelopes of a process by instance index,
+// ready to be passed to NewLRPInstances. Envelopes whose instance id is not an
+// index are ignored.
+func LRPInstanceStatsByIndex(envelopes []ContainerMetricEnvelope) map[int]*LRPInstanceStats {
+	grouped := map[int][]ContainerMetricEnvelope{}
+	for _, envelope := range envelopes {
+		index, err := strconv.Atoi(envelope.InstanceId)
+		if err != nil || index < 0 {
+			continue
+		}
+		grouped[index] = append(grouped[index], envelope)
+	}
+
+	stats := map[int]*LRPInstanceStats{}
+	for index, envelopes := range grouped {
+		stats[index] = NewLRPInstanceStats(envelopes...)
+	}
+	return stats
+}
+
+func optionalUint64(gauges map[string]float64, name string) *uint64 {
+	value, ok := gauges[name]
+	if !ok || value < 0 {
+		return nil
+	}
+
+	converted := uint64(value)
+	return &converted
+}
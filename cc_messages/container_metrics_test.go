@@ -0,0 +1,106 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Container metrics", func() {
+	var (
+		usage, entitlement cc_messages.ContainerMetricEnvelope
+	)
+
+	BeforeEach(func() {
+		usage = cc_messages.ContainerMetricEnvelope{
+			Timestamp:  time.Unix(1257894000, 0).UnixNano(),
+			InstanceId: "1",
+			Gauges: map[string]float64{
+				"cpu":                  42.5,
+				"memory":               1024,
+				"disk":                 2048,
+				"memory_quota":         4096,
+				"disk_quota":           8192,
+				"absolute_usage":       123456789,
+				"absolute_entitlement": 987654321,
+				"container_age":        60e9,
+				"log_rate_limit":       -1,
+				"rx_bytes":             100,
+				"tx_bytes":             200,
+			},
+		}
+		entitlement = cc_messages.ContainerMetricEnvelope{
+			Timestamp:  time.Unix(1257894001, 0).UnixNano(),
+			InstanceId: "1",
+			Gauges:     map[string]float64{"cpu_entitlement": 12.5, "log_rate": 512},
+		}
+	})
+
+	Describe("NewLRPInstanceStats", func() {
+		It("merges the envelopes of an instance", func() {
+			stats := cc_messages.NewLRPInstanceStats(usage, entitlement)
+
+			Expect(stats.Time).To(Equal(time.Unix(1257894001, 0)))
+			Expect(stats.CpuPercentage).To(Equal(0.425))
+			Expect(stats.MemoryBytes).To(BeEquivalentTo(1024))
+			Expect(stats.DiskBytes).To(BeEquivalentTo(2048))
+			Expect(*stats.MemoryQuotaBytes).To(BeEquivalentTo(4096))
+			Expect(*stats.DiskQuotaBytes).To(BeEquivalentTo(8192))
+			Expect(*stats.CpuEntitlementPercentage).To(Equal(0.125))
+			Expect(*stats.AbsoluteCpuUsageNanos).To(BeEquivalentTo(123456789))
+			Expect(*stats.AbsoluteCpuEntitlementNanos).To(BeEquivalentTo(987654321))
+			Expect(*stats.LogRateBytesPerSecond).To(BeEquivalentTo(512))
+			Expect(*stats.LogRateLimitBytesPerSecond).To(BeEquivalentTo(-1))
+			Expect(*stats.ContainerAgeNanos).To(BeEquivalentTo(60e9))
+			Expect(*stats.NetworkRxBytes).To(BeEquivalentTo(100))
+			Expect(*stats.NetworkTxBytes).To(BeEquivalentTo(200))
+		})
+
+		It("keeps the most recent value of a gauge", func() {
+			older := cc_messages.ContainerMetricEnvelope{Timestamp: usage.Timestamp - 1, Gauges: map[string]float64{"memory": 1}}
+			Expect(cc_messages.NewLRPInstanceStats(usage, older).MemoryBytes).To(BeEquivalentTo(1024))
+		})
+
+		It("leaves unreported values unset", func() {
+			stats := cc_messages.NewLRPInstanceStats(cc_messages.ContainerMetricEnvelope{Gauges: map[string]float64{"cpu": 1, "memory": 2, "disk": 3}})
+			Expect(stats.MemoryQuotaBytes).To(BeNil())
+			Expect(stats.CpuEntitlementPercentage).To(BeNil())
+			Expect(stats.LogRateLimitBytesPerSecond).To(BeNil())
+		})
+
+		It("returns nil without envelopes", func() {
+			Expect(cc_messages.NewLRPInstanceStats()).To(BeNil())
+		})
+	})
+
+	Describe("LRPInstanceStatsByIndex", func() {
+		It("groups envelopes by instance index", func() {
+			other := cc_messages.ContainerMetricEnvelope{InstanceId: "0", Gauges: map[string]float64{"memory": 1}}
+			bogus := cc_messages.ContainerMetricEnvelope{InstanceId: "instance-guid", Gauges: map[string]float64{"memory": 1}}
+
+			stats := cc_messages.LRPInstanceStatsByIndex([]cc_messages.ContainerMetricEnvelope{usage, other, entitlement, bogus})
+			Expect(stats).To(HaveLen(2))
+			Expect(stats[0].MemoryBytes).To(BeEquivalentTo(1))
+			Expect(*stats[1].LogRateBytesPerSecond).To(BeEquivalentTo(512))
+		})
+	})
+
+	Describe("LRPInstanceStats JSON", func() {
+		It("omits the optional values that are not set", func() {
+			stats := cc_messages.LRPInstanceStats{Time: time.Unix(0, 0).UTC(), CpuPercentage: 0.5, MemoryBytes: 1, DiskBytes: 2}
+			encoded, err := json.Marshal(stats)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(encoded).To(MatchJSON(`{"time":"1970-01-01T00:00:00Z","cpu":0.5,"mem":1,"disk":2}`))
+		})
+
+		It("decodes payloads without the optional values", func() {
+			var stats cc_messages.LRPInstanceStats
+			Expect(json.Unmarshal([]byte(`{"time":"1970-01-01T00:00:00Z","cpu":0.5,"mem":1,"disk":2}`), &stats)).To(Succeed())
+			Expect(stats.MemoryQuotaBytes).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,157 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+var ErrProcessGuidMismatch = errors.New("desired apps have different process guids")
+
+type DesiredAppChangeType string
+
+const (
+	DesiredAppUnchanged DesiredAppChangeType = "unchanged"
+	DesiredAppUpdate    DesiredAppChangeType = "update"
+	DesiredAppRecreate  DesiredAppChangeType = "recreate"
+)
+
+// Only these fields map onto a DesiredLRPUpdate; a change to any other field
+// needs the LRP to be recreated.
+var inPlaceDesiredAppFields = map[string]bool{
+	"num_instances": true,
+	"routing_info":  true,
+	"etag":          true,
+}
+
+type DesiredAppChange struct {
+	Type DesiredAppChangeType
+	// ChangedFields are the JSON names of the fields that differ, in the
+	// order they appear in DesireAppRequestFromCC. Values are left out so
+	// they are safe to log.
+	ChangedFields []string
+	// Update is only set for DesiredAppUpdate.
+	Update *models.DesiredLRPUpdate
+}
+
+// CompareDesiredApps classifies the change from one desire of a process guid
+// to the next. Empty and nil collections compare equal, and routing info is
+// compared by its decoded JSON.
+func CompareDesiredApps(previous, desired *DesireAppRequestFromCC) (DesiredAppChange, error) {
+	if previous.ProcessGuid != desired.ProcessGuid {
+		return DesiredAppChange{}, ErrProcessGuidMismatch
+	}
+
+	changedFields, err := changedDesiredAppFields(previous, desired)
+	if err != nil {
+		return DesiredAppChange{}, err
+	}
+
+	if len(changedFields) == 0 {
+		return DesiredAppChange{Type: DesiredAppUnchanged}, nil
+	}
+
+	for _, field := range changedFields {
+		if !inPlaceDesiredAppFields[field] {
+			return DesiredAppChange{Type: DesiredAppRecreate, ChangedFields: changedFields}, nil
+		}
+	}
+
+	update := &models.DesiredLRPUpdate{}
+	for _, field := range changedFields {
+		switch field {
+		case "num_instances":
+			update.SetInstances(int32(desired.NumInstances))
+		case "routing_info":
+			routes := models.Routes(desired.RoutingInfo)
+			if routes == nil {
+				routes = models.Routes{}
+			}
+			update.Routes = &routes
+		case "etag":
+			update.SetAnnotation(desired.ETag)
+		}
+	}
+
+	return DesiredAppChange{Type: DesiredAppUpdate, ChangedFields: changedFields, Update: update}, nil
+}
+
+func changedDesiredAppFields(previous, desired *DesireAppRequestFromCC) ([]string, error) {
+	changedFields := []string{}
+
+	previousValue := reflect.ValueOf(*previous)
+	desiredValue := reflect.ValueOf(*desired)
+	desiredType := desiredValue.Type()
+
+	for i := 0; i < desiredType.NumField(); i++ {
+		name, _, _ := strings.Cut(desiredType.Field(i).Tag.Get("json"), ",")
+
+		var equal bool
+		if name == "routing_info" {
+			var err error
+			equal, err = routingInfoEqual(previous.RoutingInfo, desired.RoutingInfo)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			equal = fieldValuesEqual(previousValue.Field(i), desiredValue.Field(i))
+		}
+
+		if !equal {
+			changedFields = append(changedFields, name)
+		}
+	}
+
+	return changedFields, nil
+}
+
+func fieldValuesEqual(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func routingInfoEqual(a, b CCRouteInfo) (bool, error) {
+	if len(a) != len(b) {
+		return false, nil
+	}
+
+	for key, aRoutes := range a {
+		bRoutes, ok := b[key]
+		if !ok {
+			return false, nil
+		}
+
+		aDecoded, err := decodeRoutes(aRoutes)
+		if err != nil {
+			return false, err
+		}
+		bDecoded, err := decodeRoutes(bRoutes)
+		if err != nil {
+			return false, err
+		}
+
+		if !reflect.DeepEqual(aDecoded, bDecoded) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func decodeRoutes(routes *json.RawMessage) (interface{}, error) {
+	if routes == nil {
+		return nil, nil
+	}
+
+	var decoded interface{}
+	err := json.Unmarshal(*routes, &decoded)
+	return decoded, err
+}
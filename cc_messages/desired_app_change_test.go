@@ -0,0 +1,113 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompareDesiredApps", func() {
+	var previous, desired cc_messages.DesireAppRequestFromCC
+
+	routingInfo := func(routes string) cc_messages.CCRouteInfo {
+		raw := json.RawMessage(routes)
+		return cc_messages.CCRouteInfo{cc_messages.CC_HTTP_ROUTES: &raw}
+	}
+
+	BeforeEach(func() {
+		previous = cc_messages.DesireAppRequestFromCC{
+			ProcessGuid:  "process-guid",
+			DropletUri:   "https://blobstore.example.com/droplet",
+			StartCommand: "bundle exec rackup",
+			MemoryMB:     256,
+			NumInstances: 1,
+			RoutingInfo:  routingInfo(`[{"hostname":"app.example.com","port":8080}]`),
+			ETag:         "etag-1",
+			Environment:  []*models.EnvironmentVariable{},
+		}
+		desired = previous
+		desired.Environment = nil
+	})
+
+	It("reports no change for equivalent desires", func() {
+		desired.RoutingInfo = routingInfo(`[ {"port": 8080, "hostname": "app.example.com"} ]`)
+
+		change, err := cc_messages.CompareDesiredApps(&previous, &desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change).To(Equal(cc_messages.DesiredAppChange{Type: cc_messages.DesiredAppUnchanged}))
+	})
+
+	It("updates instances, routes and the annotation in place", func() {
+		desired.NumInstances = 3
+		desired.RoutingInfo = routingInfo(`[{"hostname":"new.example.com"}]`)
+		desired.ETag = "etag-2"
+
+		change, err := cc_messages.CompareDesiredApps(&previous, &desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Type).To(Equal(cc_messages.DesiredAppUpdate))
+		Expect(change.ChangedFields).To(Equal([]string{"num_instances", "routing_info", "etag"}))
+
+		expected := &models.DesiredLRPUpdate{}
+		expected.SetInstances(3)
+		expected.SetAnnotation("etag-2")
+		routes := models.Routes(desired.RoutingInfo)
+		expected.Routes = &routes
+		Expect(change.Update).To(Equal(expected))
+	})
+
+	It("only sets the fields that changed", func() {
+		desired.ETag = "etag-2"
+
+		change, err := cc_messages.CompareDesiredApps(&previous, &desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Update.InstancesExists()).To(BeFalse())
+		Expect(change.Update.Routes).To(BeNil())
+		Expect(change.Update.GetAnnotation()).To(Equal("etag-2"))
+	})
+
+	It("clears the routes when they are removed", func() {
+		desired.RoutingInfo = nil
+
+		change, err := cc_messages.CompareDesiredApps(&previous, &desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(change.Type).To(Equal(cc_messages.DesiredAppUpdate))
+		Expect(*change.Update.Routes).To(BeEmpty())
+	})
+
+	DescribeTable("recreates when a field outside the update changes",
+		func(mutate func(*cc_messages.DesireAppRequestFromCC), field string) {
+			desired.ETag = "etag-2"
+			mutate(&desired)
+
+			change, err := cc_messages.CompareDesiredApps(&previous, &desired)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(change.Type).To(Equal(cc_messages.DesiredAppRecreate))
+			Expect(change.ChangedFields).To(Equal([]string{field, "etag"}))
+			Expect(change.Update).To(BeNil())
+		},
+		Entry("droplet", func(d *cc_messages.DesireAppRequestFromCC) { d.DropletUri = "https://blobstore.example.com/droplet-2" }, "droplet_uri"),
+		Entry("start command", func(d *cc_messages.DesireAppRequestFromCC) { d.StartCommand = "bin/start" }, "start_command"),
+		Entry("memory", func(d *cc_messages.DesireAppRequestFromCC) { d.MemoryMB = 512 }, "memory_mb"),
+		Entry("environment", func(d *cc_messages.DesireAppRequestFromCC) {
+			d.Environment = []*models.EnvironmentVariable{{Name: "FOO", Value: "bar"}}
+		}, "environment"),
+	)
+
+	It("errors on different process guids", func() {
+		desired.ProcessGuid = "other-guid"
+
+		_, err := cc_messages.CompareDesiredApps(&previous, &desired)
+		Expect(err).To(Equal(cc_messages.ErrProcessGuidMismatch))
+	})
+
+	It("errors on malformed routing info", func() {
+		desired.RoutingInfo = routingInfo(`not json`)
+
+		_, err := cc_messages.CompareDesiredApps(&previous, &desired)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,381 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+)
+
+const (
+	BuildpackLifecycleName = "buildpack"
+	DockerLifecycleName    = "docker"
+	CNBLifecycleName       = "cnb"
+
+	DefaultFileDescriptorLimit = uint64(1024)
+	DefaultLANG                = "en_US.UTF-8"
+	DefaultPort                = uint32(8080)
+
+	LRPLogSource    = "CELL"
+	AppLogSource    = "APP"
+	HealthLogSource = "HEALTH"
+
+	TrustedSystemCertificatesPath = "/etc/cf-system-certificates"
+
+	MonitorTimeout = 10 * time.Minute
+
+	lifecycleDir     = "/tmp/lifecycle"
+	staticPathPrefix = "/v1/static/"
+
+	minCpuProxy = 256
+	maxCpuProxy = 8192
+)
+
+var (
+	ErrNoLifecycleDefined             = errors.New("no lifecycle binary bundle defined for stack")
+	ErrDockerExecutionMetadataInvalid = errors.New("docker execution metadata is not valid JSON")
+	ErrVolumeMountConfigInvalid       = errors.New("volume mount config could not be encoded")
+)
+
+type DesiredLRPBuilder struct {
+	lifecycles    flags.LifecycleMap
+	fileServerURL string
+}
+
+func NewDesiredLRPBuilder(lifecycles flags.LifecycleMap, fileServerURL string) *DesiredLRPBuilder {
+	return &DesiredLRPBuilder{
+		lifecycles:    lifecycles,
+		fileServerURL: fileServerURL,
+	}
+}
+
+type dockerExecutionMetadata struct {
+	User         string       `json:"user,omitempty"`
+	ExposedPorts []dockerPort `json:"ports,omitempty"`
+}
+
+type dockerPort struct {
+	Port     uint32 `json:"Port"`
+	Protocol string `json:"Protocol"`
+}
+
+func (b *DesiredLRPBuilder) Build(desiredApp *DesireAppRequestFromCC) (*models.DesiredLRP, error) {
+	err := desiredApp.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	lifecycle := LifecycleForDesiredApp(desiredApp)
+	lifecycleURL, err := lifecycleDownloadURL(b.lifecycles, lifecycle, b.fileServerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	user := "vcap"
+	rootFS := models.PreloadedRootFS(desiredApp.Stack)
+	ports := desiredApp.Ports
+	var setup models.ActionInterface
+
+	if desiredApp.DockerImageUrl != "" {
+		metadata, err := parseDockerExecutionMetadata(desiredApp.ExecutionMetadata)
+		if err != nil {
+			return nil, err
+		}
+
+		user = "root"
+		if metadata.User != "" {
+			user = metadata.User
+		}
+
+		if len(ports) == 0 {
+			for _, port := range metadata.ExposedPorts {
+				if port.Protocol == "" || port.Protocol == "tcp" {
+					ports = append(ports, port.Port)
+				}
+			}
+		}
+
+		rootFS, err = DockerRootFS(desiredApp.DockerImageUrl)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		checksumAlgorithm, checksumValue := dropletChecksum(desiredApp.DropletHash)
+		setup = models.Serial(&models.DownloadAction{
+			From:              desiredApp.DropletUri,
+			To:                ".",
+			CacheKey:          "droplets-" + desiredApp.ProcessGuid,
+			User:              user,
+			ChecksumAlgorithm: checksumAlgorithm,
+			ChecksumValue:     checksumValue,
+		})
+	}
+
+	if len(ports) == 0 {
+		ports = []uint32{DefaultPort}
+	}
+
+	numFiles := DefaultFileDescriptorLimit
+	if desiredApp.FileDescriptors != 0 {
+		numFiles = desiredApp.FileDescriptors
+	}
+
+	volumeMounts, err := convertVolumeMounts(desiredApp.VolumeMounts)
+	if err != nil {
+		return nil, err
+	}
+
+	action := models.Codependent(&models.RunAction{
+		User:           user,
+		Path:           lifecycleDir + "/launcher",
+		Args:           []string{"app", desiredApp.StartCommand, desiredApp.ExecutionMetadata},
+		Env:            appEnvironment(desiredApp.Environment, ports[0]),
+		LogSource:      appLogSource(desiredApp.LogSource),
+		ResourceLimits: &models.ResourceLimits{OptionalNofile: &models.ResourceLimits_Nofile{Nofile: numFiles}},
+	})
+
+	routes := models.Routes(desiredApp.RoutingInfo)
+
+	desiredLRP := &models.DesiredLRP{
+		ProcessGuid:          desiredApp.ProcessGuid,
+		Domain:               AppLRPDomain,
+		RootFs:               rootFS,
+		Instances:            int32(desiredApp.NumInstances),
+		EnvironmentVariables: []*models.EnvironmentVariable{{Name: "LANG", Value: DefaultLANG}},
+		Action:               models.WrapAction(action),
+		StartTimeoutMs:       int64(desiredApp.HealthCheckTimeoutInSeconds) * 1000,
+		DiskMb:               int32(desiredApp.DiskMB),
+		MemoryMb:             int32(desiredApp.MemoryMB),
+		CpuWeight:            cpuWeight(desiredApp.MemoryMB),
+		Ports:                ports,
+		Routes:               &routes,
+		LogSource:            LRPLogSource,
+		LogGuid:              desiredApp.LogGuid,
+		Annotation:           desiredApp.ETag,
+		EgressRules:          desiredApp.EgressRules,
+		CachedDependencies: []*models.CachedDependency{{
+			From:     lifecycleURL,
+			To:       lifecycleDir,
+			CacheKey: lifecycleCacheKey(lifecycle),
+		}},
+		TrustedSystemCertificatesPath: TrustedSystemCertificatesPath,
+		VolumeMounts:                  volumeMounts,
+		Network:                       desiredApp.Network,
+		PlacementTags:                 placementTags(desiredApp.IsolationSegment),
+		ImageUsername:                 desiredApp.DockerUser,
+		ImagePassword:                 desiredApp.DockerPassword,
+		MetricTags:                    appMetricTags(desiredApp.LogGuid),
+	}
+
+	if setup != nil {
+		desiredLRP.Setup = models.WrapAction(setup)
+	}
+
+	if monitor := healthCheckMonitor(desiredApp, user, ports); monitor != nil {
+		desiredLRP.Monitor = models.WrapAction(monitor)
+	}
+
+	return desiredLRP, nil
+}
+
+// LifecycleForDesiredApp infers the docker lifecycle from the image, and
+// otherwise runs the droplet with the lifecycle CC staged it with, defaulting
+// to buildpack.
+func LifecycleForDesiredApp(desiredApp *DesireAppRequestFromCC) string {
+	switch {
+	case desiredApp.DockerImageUrl != "":
+		return DockerLifecycleName
+	case desiredApp.Lifecycle == CNBLifecycleName:
+		return CNBLifecycleName + "/" + desiredApp.Stack
+	default:
+		return BuildpackLifecycleName + "/" + desiredApp.Stack
+	}
+}
+
+func DockerRootFS(dockerImageURL string) (string, error) {
+	if strings.Contains(dockerImageURL, "://") {
+		parsed, err := url.Parse(dockerImageURL)
+		if err != nil {
+			return "", err
+		}
+		return parsed.String(), nil
+	}
+
+	host := ""
+	repository := dockerImageURL
+	if parts := strings.SplitN(dockerImageURL, "/", 2); len(parts) == 2 &&
+		(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		host, repository = parts[0], parts[1]
+	}
+
+	digest := ""
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, digest = repository[:i], repository[i+1:]
+	}
+
+	tag := ""
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+
+	// A digest pins the image, so it takes the place of any tag.
+	if digest != "" {
+		tag = digest
+	}
+
+	if host == "" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	rootFS := url.URL{Scheme: "docker", Host: host, Path: "/" + repository, Fragment: tag}
+	return rootFS.String(), nil
+}
+
+func healthCheckMonitor(desiredApp *DesireAppRequestFromCC, user string, ports []uint32) models.ActionInterface {
+	switch desiredApp.HealthCheckType {
+	case NoneHealthCheckType:
+		return nil
+	case HTTPHealthCheckType:
+		return models.Timeout(models.Parallel(healthCheckAction(user, ports[0], desiredApp.HealthCheckHTTPEndpoint)), MonitorTimeout)
+	default:
+		checks := make([]models.ActionInterface, 0, len(ports))
+		for _, port := range ports {
+			checks = append(checks, healthCheckAction(user, port, ""))
+		}
+		return models.Timeout(models.Parallel(checks...), MonitorTimeout)
+	}
+}
+
+func healthCheckAction(user string, port uint32, endpoint string) *models.RunAction {
+	args := []string{fmt.Sprintf("-port=%d", port)}
+	if endpoint != "" {
+		args = append(args, "-uri="+endpoint)
+	}
+
+	return &models.RunAction{
+		User:              user,
+		Path:              lifecycleDir + "/healthcheck",
+		Args:              args,
+		LogSource:         HealthLogSource,
+		ResourceLimits:    &models.ResourceLimits{OptionalNofile: &models.ResourceLimits_Nofile{Nofile: DefaultFileDescriptorLimit}},
+		SuppressLogOutput: true,
+	}
+}
+
+func lifecycleDownloadURL(lifecycles flags.LifecycleMap, lifecycle, fileServerURL string) (string, error) {
+	lifecyclePath, ok := lifecycles[lifecycle]
+	if !ok {
+		return "", ErrNoLifecycleDefined
+	}
+
+	parsed, err := url.Parse(lifecyclePath)
+	if err == nil && parsed.Scheme != "" {
+		return lifecyclePath, nil
+	}
+
+	return strings.TrimSuffix(fileServerURL, "/") + staticPathPrefix + strings.TrimPrefix(lifecyclePath, "/"), nil
+}
+
+func lifecycleCacheKey(lifecycle string) string {
+	return strings.Replace(lifecycle, "/", "-", 1) + "-lifecycle"
+}
+
+func parseDockerExecutionMetadata(executionMetadata string) (dockerExecutionMetadata, error) {
+	var metadata dockerExecutionMetadata
+	if executionMetadata == "" {
+		return metadata, nil
+	}
+
+	err := json.Unmarshal([]byte(executionMetadata), &metadata)
+	if err != nil {
+		return metadata, ErrDockerExecutionMetadataInvalid
+	}
+
+	return metadata, nil
+}
+
+func dropletChecksum(dropletHash string) (string, string) {
+	switch len(dropletHash) {
+	case 40:
+		return "sha1", dropletHash
+	case 64:
+		return "sha256", dropletHash
+	default:
+		return "", ""
+	}
+}
+
+func appEnvironment(env []*models.EnvironmentVariable, port uint32) []*models.EnvironmentVariable {
+	appEnv := make([]*models.EnvironmentVariable, 0, len(env)+1)
+	appEnv = append(appEnv, env...)
+	return append(appEnv, &models.EnvironmentVariable{Name: "PORT", Value: strconv.FormatUint(uint64(port), 10)})
+}
+
+func appMetricTags(logGuid string) map[string]*models.MetricTagValue {
+	return map[string]*models.MetricTagValue{
+		"source_id":           {Static: logGuid},
+		"process_instance_id": {Dynamic: models.MetricTagDynamicValueInstanceGuid},
+		"instance_id":         {Dynamic: models.MetricTagDynamicValueIndex},
+	}
+}
+
+func appLogSource(logSource string) string {
+	if logSource == "" {
+		return AppLogSource
+	}
+	return logSource
+}
+
+func placementTags(isolationSegment string) []string {
+	if isolationSegment == "" {
+		return nil
+	}
+	return []string{isolationSegment}
+}
+
+func convertVolumeMounts(volumeMounts []*VolumeMount) ([]*models.VolumeMount, error) {
+	var bbsVolumeMounts []*models.VolumeMount
+	for _, volumeMount := range volumeMounts {
+		bbsVolumeMount := &models.VolumeMount{
+			Driver:       volumeMount.Driver,
+			ContainerDir: volumeMount.ContainerDir,
+			Mode:         volumeMount.Mode,
+		}
+
+		if volumeMount.DeviceType == "shared" {
+			mountConfig := ""
+			if len(volumeMount.Device.MountConfig) > 0 {
+				configJSON, err := json.Marshal(volumeMount.Device.MountConfig)
+				if err != nil {
+					return nil, ErrVolumeMountConfigInvalid
+				}
+				mountConfig = string(configJSON)
+			}
+
+			bbsVolumeMount.Shared = &models.SharedDevice{
+				VolumeId:    volumeMount.Device.VolumeId,
+				MountConfig: mountConfig,
+			}
+		}
+
+		bbsVolumeMounts = append(bbsVolumeMounts, bbsVolumeMount)
+	}
+
+	return bbsVolumeMounts, nil
+}
+
+func cpuWeight(memoryMB int) uint32 {
+	if memoryMB > maxCpuProxy {
+		return 100
+	}
+	if memoryMB < minCpuProxy {
+		return 1
+	}
+	return uint32(99.0*(memoryMB-minCpuProxy)/(maxCpuProxy-minCpuProxy) + 1)
+}
@@ -0,0 +1,272 @@
+package cc_messages_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DesiredLRPBuilder", func() {
+	var (
+		builder    *cc_messages.DesiredLRPBuilder
+		desiredApp *cc_messages.DesireAppRequestFromCC
+		desiredLRP *models.DesiredLRP
+		buildErr   error
+	)
+
+	BeforeEach(func() {
+		lifecycles := flags.LifecycleMap{
+			"buildpack/cflinuxfs4": "buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+			"docker":               "https://blobs.example.com/docker_app_lifecycle.tgz",
+			"cnb/cflinuxfs4":       "cnb_app_lifecycle/cnb_app_lifecycle.tgz",
+		}
+		builder = cc_messages.NewDesiredLRPBuilder(lifecycles, "http://file-server.service.cf.internal:8080")
+
+		httpRoutes, err := cc_messages.CCHTTPRoutes{{Hostname: "route1", Port: 8080}}.CCRouteInfo()
+		Expect(err).NotTo(HaveOccurred())
+
+		desiredApp = &cc_messages.DesireAppRequestFromCC{
+			ProcessGuid:                 "the-app-guid-the-app-version",
+			DropletUri:                  "http://the-droplet.uri.com",
+			DropletHash:                 "3a2b5c8f0e1d4a6b7c9d2e3f4a5b6c7d8e9f0a1b",
+			Stack:                       "cflinuxfs4",
+			StartCommand:                "./run",
+			ExecutionMetadata:           `{"start_command":"./run"}`,
+			Environment:                 []*models.EnvironmentVariable{{Name: "FOO", Value: "BAR"}},
+			MemoryMB:                    128,
+			DiskMB:                      512,
+			NumInstances:                23,
+			RoutingInfo:                 httpRoutes,
+			LogGuid:                     "the-log-id",
+			HealthCheckTimeoutInSeconds: 123,
+			ETag:                        "etag-updated-at",
+			Ports:                       []uint32{8080},
+			IsolationSegment:            "segment-1",
+			VolumeMounts: []*cc_messages.VolumeMount{{
+				Driver:       "testdriver",
+				ContainerDir: "/data",
+				Mode:         "rw",
+				DeviceType:   "shared",
+				Device: cc_messages.SharedDevice{
+					VolumeId:    "volume-id",
+					MountConfig: map[string]interface{}{"key": "value"},
+				},
+			}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		desiredLRP, buildErr = builder.Build(desiredApp)
+	})
+
+	Context("with a buildpack app", func() {
+		It("builds a valid DesiredLRP", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.Validate()).To(Succeed())
+
+			Expect(desiredLRP.ProcessGuid).To(Equal("the-app-guid-the-app-version"))
+			Expect(desiredLRP.Domain).To(Equal(cc_messages.AppLRPDomain))
+			Expect(desiredLRP.RootFs).To(Equal("preloaded:cflinuxfs4"))
+			Expect(desiredLRP.Instances).To(BeEquivalentTo(23))
+			Expect(desiredLRP.MemoryMb).To(BeEquivalentTo(128))
+			Expect(desiredLRP.DiskMb).To(BeEquivalentTo(512))
+			Expect(desiredLRP.Annotation).To(Equal("etag-updated-at"))
+			Expect(desiredLRP.LogGuid).To(Equal("the-log-id"))
+			Expect(desiredLRP.LogSource).To(Equal(cc_messages.LRPLogSource))
+			Expect(desiredLRP.StartTimeoutMs).To(BeEquivalentTo(123000))
+			Expect(desiredLRP.Ports).To(Equal([]uint32{8080}))
+			Expect(desiredLRP.PlacementTags).To(Equal([]string{"segment-1"}))
+			Expect(desiredLRP.TrustedSystemCertificatesPath).To(Equal(cc_messages.TrustedSystemCertificatesPath))
+			Expect(desiredLRP.MetricTags).To(HaveKeyWithValue("source_id", &models.MetricTagValue{Static: "the-log-id"}))
+		})
+
+		It("passes the routes through", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(*desiredLRP.Routes).To(HaveKey(cc_messages.CC_HTTP_ROUTES))
+			Expect(string(*(*desiredLRP.Routes)[cc_messages.CC_HTTP_ROUTES])).To(MatchJSON(`[{"hostname":"route1","port":8080}]`))
+		})
+
+		It("caches the lifecycle from the file server", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.CachedDependencies).To(Equal([]*models.CachedDependency{{
+				From:     "http://file-server.service.cf.internal:8080/v1/static/buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+				To:       "/tmp/lifecycle",
+				CacheKey: "buildpack-cflinuxfs4-lifecycle",
+			}}))
+		})
+
+		It("downloads the droplet with its checksum", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.Setup).To(Equal(models.WrapAction(models.Serial(&models.DownloadAction{
+				From:              "http://the-droplet.uri.com",
+				To:                ".",
+				CacheKey:          "droplets-the-app-guid-the-app-version",
+				User:              "vcap",
+				ChecksumAlgorithm: "sha1",
+				ChecksumValue:     "3a2b5c8f0e1d4a6b7c9d2e3f4a5b6c7d8e9f0a1b",
+			}))))
+		})
+
+		It("runs the launcher with the app environment", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.Action).To(Equal(models.WrapAction(models.Codependent(&models.RunAction{
+				User: "vcap",
+				Path: "/tmp/lifecycle/launcher",
+				Args: []string{"app", "./run", `{"start_command":"./run"}`},
+				Env: []*models.EnvironmentVariable{
+					{Name: "FOO", Value: "BAR"},
+					{Name: "PORT", Value: "8080"},
+				},
+				LogSource:      cc_messages.AppLogSource,
+				ResourceLimits: &models.ResourceLimits{OptionalNofile: &models.ResourceLimits_Nofile{Nofile: cc_messages.DefaultFileDescriptorLimit}},
+			}))))
+		})
+
+		It("converts the volume mounts", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.VolumeMounts).To(Equal([]*models.VolumeMount{{
+				Driver:       "testdriver",
+				ContainerDir: "/data",
+				Mode:         "rw",
+				Shared: &models.SharedDevice{
+					VolumeId:    "volume-id",
+					MountConfig: `{"key":"value"}`,
+				},
+			}}))
+		})
+
+		Context("when the health check type is unspecified", func() {
+			It("monitors every port", func() {
+				desiredApp.Ports = []uint32{8080, 9090}
+				desiredLRP, buildErr = builder.Build(desiredApp)
+				Expect(buildErr).NotTo(HaveOccurred())
+
+				timeout := desiredLRP.Monitor.TimeoutAction
+				Expect(timeout).NotTo(BeNil())
+				Expect(timeout.TimeoutMs).To(BeEquivalentTo(cc_messages.MonitorTimeout.Milliseconds()))
+
+				checks := timeout.Action.ParallelAction.Actions
+				Expect(checks).To(HaveLen(2))
+				Expect(checks[0].RunAction.Args).To(Equal([]string{"-port=8080"}))
+				Expect(checks[1].RunAction.Args).To(Equal([]string{"-port=9090"}))
+				Expect(checks[0].RunAction.LogSource).To(Equal(cc_messages.HealthLogSource))
+			})
+		})
+
+		Context("when the health check type is http", func() {
+			BeforeEach(func() {
+				desiredApp.HealthCheckType = cc_messages.HTTPHealthCheckType
+				desiredApp.HealthCheckHTTPEndpoint = "/healthz"
+			})
+
+			It("checks the endpoint on the first port", func() {
+				Expect(buildErr).NotTo(HaveOccurred())
+				checks := desiredLRP.Monitor.TimeoutAction.Action.ParallelAction.Actions
+				Expect(checks).To(HaveLen(1))
+				Expect(checks[0].RunAction.Args).To(Equal([]string{"-port=8080", "-uri=/healthz"}))
+			})
+		})
+
+		Context("when the health check type is none", func() {
+			BeforeEach(func() {
+				desiredApp.HealthCheckType = cc_messages.NoneHealthCheckType
+			})
+
+			It("does not set a monitor", func() {
+				Expect(buildErr).NotTo(HaveOccurred())
+				Expect(desiredLRP.Monitor).To(BeNil())
+			})
+		})
+
+		Context("when there is no lifecycle for the stack", func() {
+			BeforeEach(func() {
+				desiredApp.Stack = "windows"
+			})
+
+			It("errors", func() {
+				Expect(buildErr).To(Equal(cc_messages.ErrNoLifecycleDefined))
+			})
+		})
+
+		Context("when the request is invalid", func() {
+			BeforeEach(func() {
+				desiredApp.MemoryMB = -1
+			})
+
+			It("returns the validation error", func() {
+				Expect(buildErr).To(BeAssignableToTypeOf(models.ValidationError{}))
+			})
+		})
+	})
+
+	Context("with a cnb app", func() {
+		BeforeEach(func() {
+			desiredApp.Lifecycle = "cnb"
+		})
+
+		It("runs the droplet with the cnb lifecycle for the stack", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.Validate()).To(Succeed())
+
+			Expect(desiredLRP.RootFs).To(Equal("preloaded:cflinuxfs4"))
+			Expect(desiredLRP.CachedDependencies[0].From).To(Equal("http://file-server.service.cf.internal:8080/v1/static/cnb_app_lifecycle/cnb_app_lifecycle.tgz"))
+			Expect(desiredLRP.CachedDependencies[0].CacheKey).To(Equal("cnb-cflinuxfs4-lifecycle"))
+		})
+	})
+
+	Context("with a docker app", func() {
+		BeforeEach(func() {
+			desiredApp.DropletUri = ""
+			desiredApp.DropletHash = ""
+			desiredApp.Stack = ""
+			desiredApp.Ports = nil
+			desiredApp.RoutingInfo = nil
+			desiredApp.DockerImageUrl = "cloudfoundry/diego-docker-app:latest"
+			desiredApp.DockerUser = "user"
+			desiredApp.DockerPassword = "password"
+			desiredApp.ExecutionMetadata = `{"user":"app","ports":[{"Port":7070,"Protocol":"tcp"},{"Port":53,"Protocol":"udp"}]}`
+		})
+
+		It("builds a valid DesiredLRP from the image", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(desiredLRP.Validate()).To(Succeed())
+
+			Expect(desiredLRP.RootFs).To(Equal("docker:///cloudfoundry/diego-docker-app#latest"))
+			Expect(desiredLRP.ImageUsername).To(Equal("user"))
+			Expect(desiredLRP.ImagePassword).To(Equal("password"))
+			Expect(desiredLRP.Setup).To(BeNil())
+			Expect(desiredLRP.Ports).To(Equal([]uint32{7070}))
+			Expect(desiredLRP.Action.CodependentAction.Actions[0].RunAction.User).To(Equal("app"))
+			Expect(desiredLRP.CachedDependencies[0].From).To(Equal("https://blobs.example.com/docker_app_lifecycle.tgz"))
+			Expect(desiredLRP.CachedDependencies[0].CacheKey).To(Equal("docker-lifecycle"))
+		})
+
+		Context("when the execution metadata is not JSON", func() {
+			BeforeEach(func() {
+				desiredApp.ExecutionMetadata = "{"
+			})
+
+			It("errors", func() {
+				Expect(buildErr).To(Equal(cc_messages.ErrDockerExecutionMetadataInvalid))
+			})
+		})
+	})
+
+	Describe("DockerRootFS", func() {
+		DescribeTable("converts image references to rootfs URLs",
+			func(image, rootFS string) {
+				Expect(cc_messages.DockerRootFS(image)).To(Equal(rootFS))
+			},
+			Entry("official image", "ubuntu", "docker:///library/ubuntu"),
+			Entry("tagged image", "cloudfoundry/diego-docker-app:v1", "docker:///cloudfoundry/diego-docker-app#v1"),
+			Entry("private registry", "registry.example.com:5000/team/app:v2", "docker://registry.example.com:5000/team/app#v2"),
+			Entry("digest image", "cloudfoundry/app@sha256:abc123", "docker:///cloudfoundry/app#sha256:abc123"),
+			Entry("tagged digest image", "registry.example.com:5000/app:v2@sha256:abc123", "docker://registry.example.com:5000/app#sha256:abc123"),
+			Entry("official digest image", "ubuntu:22.04@sha256:abc123", "docker:///library/ubuntu#sha256:abc123"),
+			Entry("existing docker URL", "docker:///cloudfoundry/app#v3", "docker:///cloudfoundry/app#v3"),
+		)
+	})
+})
@@ -17,13 +17,6 @@ const CC_HTTP_ROUTES = "http_routes"
 
 const CC_TCP_ROUTES = "tcp_routes"
 
-const (
-	TaskStatePending   = "PENDING"
-	TaskStateRunning   = "RUNNING"
-	TaskStateCanceling = "CANCELING"
-	TaskStateSucceeded = "SUCCEEDED"
-)
-
 type DesireAppRequestFromCC struct {
 	ProcessGuid                 string                        `json:"process_guid"`
 	DropletUri                  string                        `json:"droplet_uri"`
@@ -34,6 +27,7 @@ type DesireAppRequestFromCC struct {
 	DockerPassword              string                        `json:"docker_password,omitempty"`
 	DockerEmail                 string                        `json:"docker_email,omitempty"`
 	Stack                       string                        `json:"stack"`
+	Lifecycle                   string                        `json:"lifecycle,omitempty"`
 	StartCommand                string                        `json:"start_command"`
 	ExecutionMetadata           string                        `json:"execution_metadata"`
 	Environment                 []*models.EnvironmentVariable `json:"environment"`
@@ -122,9 +116,9 @@ type CCDesiredAppFingerprint struct {
 }
 
 type CCTaskState struct {
-	TaskGuid              string `json:"task_guid"`
-	State                 string `json:"state"`
-	CompletionCallbackUrl string `json:"completion_callback"`
+	TaskGuid              string    `json:"task_guid"`
+	State                 TaskState `json:"state"`
+	CompletionCallbackUrl string    `json:"completion_callback"`
 }
 
 type CCDesiredStateFingerprintResponse struct {
@@ -26,4 +26,94 @@ var _ = Describe("CC Messages", func() {
 			Expect(string(*json)).To(MatchJSON(expectedJson))
 		})
 	})
+
+	Describe("CCRouteInfo", func() {
+		rawMessage := func(payload string) *json.RawMessage {
+			raw := json.RawMessage(payload)
+			return &raw
+		}
+
+		var routeInfo cc_messages.CCRouteInfo
+
+		BeforeEach(func() {
+			routeInfo = cc_messages.CCRouteInfo{
+				cc_messages.CC_HTTP_ROUTES: rawMessage(`[{"hostname":"route1","port":8080}]`),
+				cc_messages.CC_TCP_ROUTES:  rawMessage(`[{"router_group_guid":"group-1","external_port":5222,"container_port":60000}]`),
+				"diego-ssh":                rawMessage(`{"container_port":2222}`),
+			}
+		})
+
+		Describe("HTTPRoutes", func() {
+			It("decodes the http routes", func() {
+				Expect(routeInfo.HTTPRoutes()).To(Equal(cc_messages.CCHTTPRoutes{
+					{Hostname: "route1", Port: 8080},
+				}))
+			})
+
+			It("returns no routes when the key is missing", func() {
+				Expect(cc_messages.CCRouteInfo{}.HTTPRoutes()).To(BeEmpty())
+			})
+
+			It("errors when the payload is malformed", func() {
+				routeInfo[cc_messages.CC_HTTP_ROUTES] = rawMessage(`{"hostname":"route1"}`)
+				_, err := routeInfo.HTTPRoutes()
+				Expect(err).To(BeAssignableToTypeOf(cc_messages.ErrMalformedRoutes{}))
+				Expect(err.(cc_messages.ErrMalformedRoutes).Key).To(Equal(cc_messages.CC_HTTP_ROUTES))
+			})
+		})
+
+		Describe("TCPRoutes", func() {
+			It("decodes the tcp routes", func() {
+				Expect(routeInfo.TCPRoutes()).To(Equal(cc_messages.CCTCPRoutes{
+					{RouterGroupGuid: "group-1", ExternalPort: 5222, ContainerPort: 60000},
+				}))
+			})
+
+			It("errors when the payload is malformed", func() {
+				routeInfo[cc_messages.CC_TCP_ROUTES] = rawMessage(`[{"external_port":"high"}]`)
+				_, err := routeInfo.TCPRoutes()
+				Expect(err).To(MatchError(ContainSubstring("malformed tcp_routes")))
+			})
+		})
+
+		Describe("UnknownRoutes", func() {
+			It("returns only the keys other than http and tcp routes", func() {
+				Expect(routeInfo.UnknownRoutes()).To(Equal(cc_messages.CCRouteInfo{
+					"diego-ssh": rawMessage(`{"container_port":2222}`),
+				}))
+			})
+		})
+
+		Describe("WithRoutes", func() {
+			It("replaces the given routes and keeps the other keys", func() {
+				merged, err := routeInfo.WithRoutes(cc_messages.CCHTTPRoutes{{Hostname: "route2"}}, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(merged.HTTPRoutes()).To(Equal(cc_messages.CCHTTPRoutes{{Hostname: "route2"}}))
+				Expect(merged.TCPRoutes()).To(Equal(cc_messages.CCTCPRoutes{
+					{RouterGroupGuid: "group-1", ExternalPort: 5222, ContainerPort: 60000},
+				}))
+				Expect(string(*merged["diego-ssh"])).To(MatchJSON(`{"container_port":2222}`))
+			})
+
+			It("does not modify the original routing info", func() {
+				_, err := routeInfo.WithRoutes(cc_messages.CCHTTPRoutes{}, cc_messages.CCTCPRoutes{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(routeInfo.HTTPRoutes()).To(HaveLen(1))
+			})
+		})
+
+		Describe("NewCCRouteInfo", func() {
+			It("merges http and tcp routes", func() {
+				routeInfo, err := cc_messages.NewCCRouteInfo(
+					cc_messages.CCHTTPRoutes{{Hostname: "route1"}},
+					cc_messages.CCTCPRoutes{{RouterGroupGuid: "group-1", ExternalPort: 5222}},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(routeInfo).To(HaveLen(2))
+				Expect(string(*routeInfo[cc_messages.CC_HTTP_ROUTES])).To(MatchJSON(`[{"hostname":"route1"}]`))
+				Expect(string(*routeInfo[cc_messages.CC_TCP_ROUTES])).To(MatchJSON(`[{"router_group_guid":"group-1","external_port":5222}]`))
+			})
+		})
+	})
 })
@@ -0,0 +1,461 @@
+package fakecc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+)
+
+type Route string
+
+const (
+	DesiredAppsRoute         Route = "desired_apps"
+	FingerprintsRoute        Route = "fingerprints"
+	TaskStatesRoute          Route = "task_states"
+	StagingCompletedRoute    Route = "staging_completed"
+	TaskCompletedRoute       Route = "task_completed"
+	AppCrashedRoute          Route = "app_crashed"
+	AppReschedulingRoute     Route = "app_rescheduling"
+	AppReadinessChangedRoute Route = "app_readiness_changed"
+)
+
+const (
+	bulkAppsPath       = "/internal/bulk/apps"
+	bulkTaskStatesPath = "/internal/v3/bulk/task_states"
+	stagingPathPrefix  = "/internal/v3/staging/"
+	tasksPathPrefix    = "/internal/v3/tasks/"
+	appsPathPrefix     = "/internal/v4/apps/"
+)
+
+const defaultBatchSize = 500
+
+var (
+	errInvalidBatchSize = errors.New("invalid batch_size")
+	errInvalidToken     = errors.New("invalid token")
+)
+
+type StagingCompleted struct {
+	StagingGuid string
+	Response    cc_messages.StagingResponseForCC
+}
+
+type TaskCompleted struct {
+	TaskGuid string
+	Response cc_messages.TaskFailResponseForCC
+}
+
+type AppCrashed struct {
+	ProcessGuid string
+	Request     cc_messages.AppCrashedRequest
+}
+
+type AppRescheduling struct {
+	ProcessGuid string
+	Request     cc_messages.AppReschedulingRequest
+}
+
+type AppReadinessChanged struct {
+	ProcessGuid string
+	Request     cc_messages.AppReadinessChangedRequest
+}
+
+type failure struct {
+	statusCode int
+	remaining  int
+}
+
+// Server is an in-memory Cloud Controller serving the bulk and callback APIs
+// that Diego components talk to.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	username string
+	password string
+	latency  time.Duration
+	failures map[Route]*failure
+	requests map[Route]int
+
+	appGuids  []string
+	apps      map[string]cc_messages.DesireAppRequestFromCC
+	taskGuids []string
+	tasks     map[string]cc_messages.CCTaskState
+
+	stagingCompleted    []StagingCompleted
+	taskCompleted       []TaskCompleted
+	appCrashed          []AppCrashed
+	appRescheduling     []AppRescheduling
+	appReadinessChanged []AppReadinessChanged
+}
+
+func New() *Server {
+	s := &Server{
+		failures: map[Route]*failure{},
+		requests: map[Route]int{},
+		apps:     map[string]cc_messages.DesireAppRequestFromCC{},
+		tasks:    map[string]cc_messages.CCTaskState{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) SetCredentials(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username, s.password = username, password
+}
+
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// FailNext makes the next times requests to route respond with statusCode.
+// A times of zero or less clears any failures pending for route.
+func (s *Server) FailNext(route Route, statusCode, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if times <= 0 {
+		delete(s.failures, route)
+		return
+	}
+	s.failures[route] = &failure{statusCode: statusCode, remaining: times}
+}
+
+func (s *Server) RequestCount(route Route) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[route]
+}
+
+func (s *Server) SetApps(apps ...cc_messages.DesireAppRequestFromCC) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, app := range apps {
+		if _, ok := s.apps[app.ProcessGuid]; !ok {
+			s.appGuids = append(s.appGuids, app.ProcessGuid)
+		}
+		s.apps[app.ProcessGuid] = app
+	}
+}
+
+func (s *Server) RemoveApp(processGuid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.apps, processGuid)
+	s.appGuids = remove(s.appGuids, processGuid)
+}
+
+func (s *Server) SetTasks(tasks ...cc_messages.CCTaskState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range tasks {
+		if _, ok := s.tasks[task.TaskGuid]; !ok {
+			s.taskGuids = append(s.taskGuids, task.TaskGuid)
+		}
+		s.tasks[task.TaskGuid] = task
+	}
+}
+
+func (s *Server) RemoveTask(taskGuid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, taskGuid)
+	s.taskGuids = remove(s.taskGuids, taskGuid)
+}
+
+func (s *Server) StagingCompletedURL(stagingGuid string) string {
+	return s.URL + stagingPathPrefix + stagingGuid + "/build_completed"
+}
+
+func (s *Server) TaskCompletedURL(taskGuid string) string {
+	return s.URL + tasksPathPrefix + taskGuid + "/completed"
+}
+
+func (s *Server) AppCrashedURL(processGuid string) string {
+	return s.URL + appsPathPrefix + processGuid + "/crashed"
+}
+
+func (s *Server) AppReschedulingURL(processGuid string) string {
+	return s.URL + appsPathPrefix + processGuid + "/rescheduling"
+}
+
+func (s *Server) AppReadinessChangedURL(processGuid string) string {
+	return s.URL + appsPathPrefix + processGuid + "/readiness_changed"
+}
+
+func (s *Server) StagingCompleted() []StagingCompleted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StagingCompleted{}, s.stagingCompleted...)
+}
+
+func (s *Server) TaskCompleted() []TaskCompleted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskCompleted{}, s.taskCompleted...)
+}
+
+func (s *Server) AppCrashed() []AppCrashed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AppCrashed{}, s.appCrashed...)
+}
+
+func (s *Server) AppRescheduling() []AppRescheduling {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AppRescheduling{}, s.appRescheduling...)
+}
+
+func (s *Server) AppReadinessChanged() []AppReadinessChanged {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AppReadinessChanged{}, s.appReadinessChanged...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	route, guid, ok := match(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests[route]++
+	latency := s.latency
+	username, password := s.username, s.password
+	statusCode := s.injectedFailure(route)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if username != "" {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if statusCode != 0 {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	switch route {
+	case DesiredAppsRoute:
+		s.serveDesiredApps(w, r)
+	case FingerprintsRoute:
+		s.serveFingerprints(w, r)
+	case TaskStatesRoute:
+		s.serveTaskStates(w, r)
+	case StagingCompletedRoute:
+		record(w, r, func(response cc_messages.StagingResponseForCC) {
+			s.stagingCompleted = append(s.stagingCompleted, StagingCompleted{StagingGuid: guid, Response: response})
+		}, &s.mu)
+	case TaskCompletedRoute:
+		record(w, r, func(response cc_messages.TaskFailResponseForCC) {
+			s.taskCompleted = append(s.taskCompleted, TaskCompleted{TaskGuid: guid, Response: response})
+		}, &s.mu)
+	case AppCrashedRoute:
+		record(w, r, func(request cc_messages.AppCrashedRequest) {
+			s.appCrashed = append(s.appCrashed, AppCrashed{ProcessGuid: guid, Request: request})
+		}, &s.mu)
+	case AppReschedulingRoute:
+		record(w, r, func(request cc_messages.AppReschedulingRequest) {
+			s.appRescheduling = append(s.appRescheduling, AppRescheduling{ProcessGuid: guid, Request: request})
+		}, &s.mu)
+	case AppReadinessChangedRoute:
+		record(w, r, func(request cc_messages.AppReadinessChangedRequest) {
+			s.appReadinessChanged = append(s.appReadinessChanged, AppReadinessChanged{ProcessGuid: guid, Request: request})
+		}, &s.mu)
+	}
+}
+
+func (s *Server) injectedFailure(route Route) int {
+	f, ok := s.failures[route]
+	if !ok {
+		return 0
+	}
+
+	f.remaining--
+	if f.remaining <= 0 {
+		delete(s.failures, route)
+	}
+	return f.statusCode
+}
+
+func (s *Server) serveDesiredApps(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	guids, token, err := page(r, s.appGuids)
+	apps := []cc_messages.DesireAppRequestFromCC{}
+	for _, guid := range guids {
+		apps = append(apps, s.apps[guid])
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, cc_messages.CCDesiredStateServerResponse{Apps: apps, CCBulkToken: token})
+}
+
+func (s *Server) serveFingerprints(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	guids, token, err := page(r, s.appGuids)
+	fingerprints := []cc_messages.CCDesiredAppFingerprint{}
+	for _, guid := range guids {
+		fingerprints = append(fingerprints, cc_messages.CCDesiredAppFingerprint{ProcessGuid: guid, ETag: s.apps[guid].ETag})
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, cc_messages.CCDesiredStateFingerprintResponse{Fingerprints: fingerprints, CCBulkToken: token})
+}
+
+func (s *Server) serveTaskStates(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	guids, token, err := page(r, s.taskGuids)
+	states := []cc_messages.CCTaskState{}
+	for _, guid := range guids {
+		states = append(states, s.tasks[guid])
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, cc_messages.CCTaskStatesResponse{TaskStates: states, CCBulkToken: token})
+}
+
+// page treats the token id as the offset of the next item to serve.
+func page(r *http.Request, guids []string) ([]string, *json.RawMessage, error) {
+	batchSize := defaultBatchSize
+	if param := r.URL.Query().Get("batch_size"); param != "" {
+		size, err := strconv.Atoi(param)
+		if err != nil || size <= 0 {
+			return nil, nil, errInvalidBatchSize
+		}
+		batchSize = size
+	}
+
+	token := cc_messages.CCBulkToken{}
+	if param := r.URL.Query().Get("token"); param != "" {
+		err := json.Unmarshal([]byte(param), &token)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if token.Id < 0 {
+		return nil, nil, errInvalidToken
+	}
+
+	start := token.Id
+	if start > len(guids) {
+		start = len(guids)
+	}
+	end := start + batchSize
+	if end > len(guids) {
+		end = len(guids)
+	}
+
+	encoded, err := json.Marshal(cc_messages.CCBulkToken{Id: end})
+	if err != nil {
+		return nil, nil, err
+	}
+	next := json.RawMessage(encoded)
+	return append([]string{}, guids[start:end]...), &next, nil
+}
+
+func match(r *http.Request) (Route, string, bool) {
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && path == bulkAppsPath:
+		if r.URL.Query().Get("format") == "fingerprint" {
+			return FingerprintsRoute, "", true
+		}
+		return DesiredAppsRoute, "", true
+	case r.Method == http.MethodGet && path == bulkTaskStatesPath:
+		return TaskStatesRoute, "", true
+	case r.Method != http.MethodPost:
+		return "", "", false
+	}
+
+	if guid, ok := guidBetween(path, stagingPathPrefix, "/build_completed"); ok {
+		return StagingCompletedRoute, guid, true
+	}
+	if guid, ok := guidBetween(path, tasksPathPrefix, "/completed"); ok {
+		return TaskCompletedRoute, guid, true
+	}
+	if guid, ok := guidBetween(path, appsPathPrefix, "/crashed"); ok {
+		return AppCrashedRoute, guid, true
+	}
+	if guid, ok := guidBetween(path, appsPathPrefix, "/rescheduling"); ok {
+		return AppReschedulingRoute, guid, true
+	}
+	if guid, ok := guidBetween(path, appsPathPrefix, "/readiness_changed"); ok {
+		return AppReadinessChangedRoute, guid, true
+	}
+	return "", "", false
+}
+
+func guidBetween(path, prefix, suffix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+
+	guid := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	if guid == "" || strings.Contains(guid, "/") {
+		return "", false
+	}
+	return guid, true
+}
+
+func record[T any](w http.ResponseWriter, r *http.Request, store func(T), mu *sync.Mutex) {
+	var payload T
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	store(payload)
+	mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
+
+func remove(guids []string, guid string) []string {
+	for i, g := range guids {
+		if g == guid {
+			return append(guids[:i:i], guids[i+1:]...)
+		}
+	}
+	return guids
+}
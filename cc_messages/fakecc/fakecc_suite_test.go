@@ -0,0 +1,13 @@
+package fakecc_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFakeCC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fake CC Suite")
+}
@@ -0,0 +1,190 @@
+package fakecc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/bulk"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/fakecc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func post(url string, payload interface{}) *http.Response {
+	encoded, err := json.Marshal(payload)
+	Expect(err).NotTo(HaveOccurred())
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(encoded))
+	Expect(err).NotTo(HaveOccurred())
+	req.SetBasicAuth("internal_user", "internal_password")
+
+	resp, err := http.DefaultClient.Do(req)
+	Expect(err).NotTo(HaveOccurred())
+	resp.Body.Close()
+	return resp
+}
+
+var _ = Describe("Fake CC", func() {
+	var (
+		server *fakecc.Server
+		client *bulk.Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		server = fakecc.New()
+		server.SetCredentials("internal_user", "internal_password")
+		client = bulk.NewClient(bulk.Config{
+			BaseURL:       server.URL,
+			Username:      "internal_user",
+			Password:      "internal_password",
+			PageSize:      2,
+			MaxRetries:    1,
+			RetryInterval: time.Millisecond,
+		})
+		ctx = context.Background()
+
+		server.SetApps(
+			cc_messages.DesireAppRequestFromCC{ProcessGuid: "a", ETag: "a-1"},
+			cc_messages.DesireAppRequestFromCC{ProcessGuid: "b", ETag: "b-1"},
+			cc_messages.DesireAppRequestFromCC{ProcessGuid: "c", ETag: "c-1"},
+		)
+		server.SetTasks(cc_messages.CCTaskState{TaskGuid: "t", State: cc_messages.TaskStateRunning})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("bulk endpoints", func() {
+		It("serves fingerprints in pages", func() {
+			fingerprints, err := bulk.All(ctx, bulk.NewIterator(client, bulk.Fingerprints))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fingerprints).To(Equal([]cc_messages.CCDesiredAppFingerprint{
+				{ProcessGuid: "a", ETag: "a-1"},
+				{ProcessGuid: "b", ETag: "b-1"},
+				{ProcessGuid: "c", ETag: "c-1"},
+			}))
+			Expect(server.RequestCount(fakecc.FingerprintsRoute)).To(Equal(2))
+		})
+
+		It("serves desired apps and reflects updates", func() {
+			server.SetApps(cc_messages.DesireAppRequestFromCC{ProcessGuid: "b", ETag: "b-2"})
+			server.RemoveApp("a")
+
+			apps, err := bulk.All(ctx, bulk.NewIterator(client, bulk.DesiredApps))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps).To(Equal([]cc_messages.DesireAppRequestFromCC{
+				{ProcessGuid: "b", ETag: "b-2"},
+				{ProcessGuid: "c", ETag: "c-1"},
+			}))
+		})
+
+		It("serves task states", func() {
+			states, err := bulk.All(ctx, bulk.NewIterator(client, bulk.TaskStates))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(states).To(Equal([]cc_messages.CCTaskState{{TaskGuid: "t", State: cc_messages.TaskStateRunning}}))
+		})
+
+		It("rejects negative tokens", func() {
+			req, err := http.NewRequest(http.MethodGet, server.URL+`/internal/bulk/apps?batch_size=2&token={"id":-1}`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.SetBasicAuth("internal_user", "internal_password")
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("requires credentials", func() {
+			unauthorized := bulk.NewClient(bulk.Config{BaseURL: server.URL})
+			_, err := bulk.All(ctx, bulk.NewIterator(unauthorized, bulk.Fingerprints))
+			Expect(err).To(MatchError(bulk.ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized}))
+		})
+	})
+
+	Describe("callbacks", func() {
+		It("records staging and task completions", func() {
+			result := json.RawMessage(`{"lifecycle_type":"buildpack"}`)
+			Expect(post(server.StagingCompletedURL("staging-guid"), cc_messages.StagingResponseForCC{Result: &result}).StatusCode).To(Equal(http.StatusOK))
+			Expect(post(server.TaskCompletedURL("task-guid"), cc_messages.TaskFailResponseForCC{TaskGuid: "task-guid", Failed: true, FailureReason: "oom"}).StatusCode).To(Equal(http.StatusOK))
+
+			Expect(server.StagingCompleted()).To(HaveLen(1))
+			Expect(server.StagingCompleted()[0].StagingGuid).To(Equal("staging-guid"))
+			Expect(string(*server.StagingCompleted()[0].Response.Result)).To(MatchJSON(result))
+			Expect(server.TaskCompleted()).To(Equal([]fakecc.TaskCompleted{{
+				TaskGuid: "task-guid",
+				Response: cc_messages.TaskFailResponseForCC{TaskGuid: "task-guid", Failed: true, FailureReason: "oom"},
+			}}))
+		})
+
+		It("records app instance events", func() {
+			post(server.AppCrashedURL("process-guid"), cc_messages.AppCrashedRequest{Instance: "instance-guid", Index: 1, Reason: "CRASHED", ExitStatus: 137})
+			post(server.AppReschedulingURL("process-guid"), cc_messages.AppReschedulingRequest{Instance: "instance-guid", Index: 1})
+			post(server.AppReadinessChangedURL("process-guid"), cc_messages.AppReadinessChangedRequest{Instance: "instance-guid", Index: 1, Ready: true})
+
+			Expect(server.AppCrashed()).To(Equal([]fakecc.AppCrashed{{
+				ProcessGuid: "process-guid",
+				Request:     cc_messages.AppCrashedRequest{Instance: "instance-guid", Index: 1, Reason: "CRASHED", ExitStatus: 137},
+			}}))
+			Expect(server.AppRescheduling()).To(HaveLen(1))
+			Expect(server.AppReadinessChanged()[0].Request.Ready).To(BeTrue())
+		})
+
+		It("rejects unauthenticated requests and malformed payloads", func() {
+			resp, err := http.Post(server.AppCrashedURL("process-guid"), "application/json", bytes.NewBufferString("{"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+			server.SetCredentials("", "")
+			resp, err = http.Post(server.AppCrashedURL("process-guid"), "application/json", bytes.NewBufferString("{"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(server.AppCrashed()).To(BeEmpty())
+		})
+	})
+
+	Describe("fault injection", func() {
+		It("fails the configured number of requests", func() {
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusServiceUnavailable, 2)
+
+			Expect(post(server.AppCrashedURL("p"), cc_messages.AppCrashedRequest{}).StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(post(server.AppCrashedURL("p"), cc_messages.AppCrashedRequest{}).StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(post(server.AppCrashedURL("p"), cc_messages.AppCrashedRequest{}).StatusCode).To(Equal(http.StatusOK))
+			Expect(server.AppCrashed()).To(HaveLen(1))
+			Expect(server.RequestCount(fakecc.AppCrashedRoute)).To(Equal(3))
+		})
+
+		It("clears pending failures when told to fail no requests", func() {
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusServiceUnavailable, 2)
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusServiceUnavailable, 0)
+
+			Expect(post(server.AppCrashedURL("p"), cc_messages.AppCrashedRequest{}).StatusCode).To(Equal(http.StatusOK))
+
+			server.FailNext(fakecc.AppCrashedRoute, http.StatusServiceUnavailable, -1)
+			Expect(post(server.AppCrashedURL("p"), cc_messages.AppCrashedRequest{}).StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("lets clients retry through injected failures", func() {
+			server.FailNext(fakecc.FingerprintsRoute, http.StatusInternalServerError, 1)
+
+			fingerprints, err := bulk.All(ctx, bulk.NewIterator(client, bulk.Fingerprints))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fingerprints).To(HaveLen(3))
+		})
+
+		It("delays responses", func() {
+			server.SetLatency(50 * time.Millisecond)
+
+			start := time.Now()
+			post(server.AppReadinessChangedURL("p"), cc_messages.AppReadinessChangedRequest{})
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+	})
+})
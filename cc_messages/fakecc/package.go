@@ -0,0 +1 @@
+package fakecc // import "code.cloudfoundry.org/runtimeschema/cc_messages/fakecc"
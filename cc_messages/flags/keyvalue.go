@@ -0,0 +1,130 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrKeyValueFormatInvalid = newKeyValueError("not of the form 'key:value'")
+	ErrKeyEmpty              = newKeyValueError("empty key")
+	ErrValueEmpty            = newKeyValueError("empty value")
+)
+
+type keyValueError struct {
+	msg string
+}
+
+func newKeyValueError(msg string) error {
+	return keyValueError{msg: msg}
+}
+
+func (e keyValueError) Error() string {
+	return "Invalid key:value value: " + e.msg
+}
+
+type Validator func(string) error
+
+// keyValueErrors are the errors a map flag reports for malformed values, so
+// that each flag type keeps its own sentinels.
+type keyValueErrors struct {
+	formatInvalid error
+	keyEmpty      error
+	valueEmpty    error
+}
+
+func splitKeyValue(value string, errs keyValueErrors) (string, string, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return "", "", errs.formatInvalid
+	}
+
+	if parts[0] == "" {
+		return "", "", errs.keyEmpty
+	}
+
+	if parts[1] == "" {
+		return "", "", errs.valueEmpty
+	}
+
+	return parts[0], parts[1], nil
+}
+
+func setKeyValue(m map[string]string, value string, errs keyValueErrors, validateKey, validateValue Validator) error {
+	key, val, err := splitKeyValue(value, errs)
+	if err != nil {
+		return err
+	}
+
+	if validateKey != nil {
+		if err := validateKey(key); err != nil {
+			return err
+		}
+	}
+
+	if validateValue != nil {
+		if err := validateValue(val); err != nil {
+			return err
+		}
+	}
+
+	m[key] = val
+	return nil
+}
+
+// KeyValueMap is a repeatable flag of "key:value" pairs whose keys and values
+// are checked by the given validators. A repeated key keeps the last value.
+type KeyValueMap struct {
+	Values        map[string]string
+	ValidateKey   Validator
+	ValidateValue Validator
+}
+
+func NewKeyValueMap(validateKey, validateValue Validator) *KeyValueMap {
+	return &KeyValueMap{
+		Values:        map[string]string{},
+		ValidateKey:   validateKey,
+		ValidateValue: validateValue,
+	}
+}
+
+func (m *KeyValueMap) String() string {
+	return fmt.Sprintf("%v", m.Values)
+}
+
+func (m *KeyValueMap) Set(value string) error {
+	if m.Values == nil {
+		m.Values = map[string]string{}
+	}
+
+	errs := keyValueErrors{
+		formatInvalid: ErrKeyValueFormatInvalid,
+		keyEmpty:      ErrKeyEmpty,
+		valueEmpty:    ErrValueEmpty,
+	}
+	return setKeyValue(m.Values, value, errs, m.ValidateKey, m.ValidateValue)
+}
+
+var (
+	ErrURLInvalid      = errors.New("not a valid url")
+	ErrDurationInvalid = errors.New("not a valid duration")
+)
+
+func ValidURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("%w: %q", ErrURLInvalid, value)
+	}
+	return nil
+}
+
+func ValidDuration(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return fmt.Errorf("%w: %q", ErrDurationInvalid, value)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package flags_test
+
+import (
+	"errors"
+	"flag"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyValueMap", func() {
+	var domains *flags.KeyValueMap
+
+	BeforeEach(func() {
+		domains = flags.NewKeyValueMap(nil, flags.ValidDuration)
+	})
+
+	It("collects repeated flags", func() {
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.Var(domains, "domainTTL", "domain:ttl")
+
+		err := flagSet.Parse([]string{"-domainTTL", "cf-apps:2m", "-domainTTL", "cf-tasks:30s", "-domainTTL", "cf-apps:5m"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domains.Values).To(Equal(map[string]string{"cf-apps": "5m", "cf-tasks": "30s"}))
+	})
+
+	It("works from its zero value", func() {
+		var m flags.KeyValueMap
+		Expect(m.Set("a:b")).To(Succeed())
+		Expect(m.Values).To(HaveKeyWithValue("a", "b"))
+	})
+
+	It("validates keys and values", func() {
+		upper := errors.New("key must be lower case")
+		domains.ValidateKey = func(key string) error {
+			if key != "cf-apps" {
+				return upper
+			}
+			return nil
+		}
+
+		Expect(domains.Set("CF-APPS:2m")).To(Equal(upper))
+		Expect(errors.Is(domains.Set("cf-apps:forever"), flags.ErrDurationInvalid)).To(BeTrue())
+		Expect(domains.Values).To(BeEmpty())
+	})
+
+	DescribeTable("rejects malformed values",
+		func(value string, expected error) {
+			Expect(domains.Set(value)).To(Equal(expected))
+		},
+		Entry("no separator", "cf-apps", flags.ErrKeyValueFormatInvalid),
+		Entry("empty key", ":2m", flags.ErrKeyEmpty),
+		Entry("empty value", "cf-apps:", flags.ErrValueEmpty),
+	)
+
+	It("validates urls", func() {
+		Expect(flags.ValidURL("https://example.com/rootfs.tar")).To(Succeed())
+		Expect(errors.Is(flags.ValidURL("rootfs.tar"), flags.ErrURLInvalid)).To(BeTrue())
+	})
+})
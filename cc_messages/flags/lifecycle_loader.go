@@ -0,0 +1,162 @@
+package flags
+
+import (
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultLifecycleConfigSection = "lifecycles"
+	DefaultLifecycleEnvVar        = "LIFECYCLES"
+)
+
+var ErrLifecycleConfigInvalid = newLifecycleError("config section is not a map or list of lifecycles")
+
+// LifecycleLoader assembles a LifecycleMap from a config file, an environment
+// variable and flags. A lifecycle set in more than one place takes its path
+// from the flags first, then the environment, then the config file. Within a
+// single source, listing a lifecycle twice is an error.
+type LifecycleLoader struct {
+	// ConfigFile is a JSON or YAML file holding the lifecycles under
+	// ConfigSection, either as a map of names to paths or as a list of
+	// "lifecycle-name:path" entries. It is skipped when empty.
+	ConfigFile    string
+	ConfigSection string
+	// EnvVar names the variable holding comma or whitespace separated
+	// "lifecycle-name:path" entries. It defaults to DefaultLifecycleEnvVar,
+	// so the environment is always consulted.
+	EnvVar string
+	Flags  LifecycleMap
+}
+
+func (l LifecycleLoader) Load() (LifecycleMap, error) {
+	lifecycles := LifecycleMap{}
+
+	if l.ConfigFile != "" {
+		data, err := os.ReadFile(l.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+
+		section := l.ConfigSection
+		if section == "" {
+			section = DefaultLifecycleConfigSection
+		}
+
+		fromConfig, err := LifecyclesFromConfig(data, section)
+		if err != nil {
+			return nil, err
+		}
+		lifecycles.merge(fromConfig)
+	}
+
+	envVar := l.EnvVar
+	if envVar == "" {
+		envVar = DefaultLifecycleEnvVar
+	}
+	fromEnv, err := LifecyclesFromEnv(os.Getenv(envVar))
+	if err != nil {
+		return nil, err
+	}
+	lifecycles.merge(fromEnv)
+
+	lifecycles.merge(l.Flags)
+	return lifecycles, nil
+}
+
+// LifecyclesFromConfig reads the lifecycles under section of a JSON or YAML
+// document. A missing section yields no lifecycles.
+func LifecyclesFromConfig(data []byte, section string) (LifecycleMap, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	lifecycles := LifecycleMap{}
+	if len(document.Content) == 0 {
+		return lifecycles, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, ErrLifecycleConfigInvalid
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != section {
+			continue
+		}
+
+		value := root.Content[i+1]
+		switch value.Kind {
+		case yaml.MappingNode:
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name, path := value.Content[j], value.Content[j+1]
+				if name.Kind != yaml.ScalarNode || path.Kind != yaml.ScalarNode {
+					return nil, ErrLifecycleConfigInvalid
+				}
+
+				err := lifecycles.add(name.Value + ":" + path.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case yaml.SequenceNode:
+			for _, entry := range value.Content {
+				if entry.Kind != yaml.ScalarNode {
+					return nil, ErrLifecycleConfigInvalid
+				}
+
+				err := lifecycles.add(entry.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, ErrLifecycleConfigInvalid
+		}
+	}
+
+	return lifecycles, nil
+}
+
+func LifecyclesFromEnv(value string) (LifecycleMap, error) {
+	lifecycles := LifecycleMap{}
+
+	entries := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	for _, entry := range entries {
+		err := lifecycles.add(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lifecycles, nil
+}
+
+func (s LifecycleMap) add(value string) error {
+	name, path, err := splitKeyValue(value, lifecycleErrors)
+	if err != nil {
+		return err
+	}
+
+	if existing, ok := s[name]; ok {
+		if existing == path {
+			return namedLifecycleError(ErrLifecycleDuplicate, name)
+		}
+		return namedLifecycleError(ErrLifecyclePathConflict, name)
+	}
+
+	s[name] = path
+	return nil
+}
+
+func (s LifecycleMap) merge(other LifecycleMap) {
+	for name, path := range other {
+		s[name] = path
+	}
+}
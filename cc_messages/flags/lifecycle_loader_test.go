@@ -0,0 +1,155 @@
+package flags_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LifecycleLoader", func() {
+	Describe("LifecyclesFromConfig", func() {
+		It("reads a JSON map", func() {
+			lifecycles, err := flags.LifecyclesFromConfig([]byte(`{
+				"other": {"ignored": true},
+				"lifecycles": {
+					"buildpack/cflinuxfs4": "buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+					"docker": "docker_app_lifecycle/docker_app_lifecycle.tgz"
+				}
+			}`), "lifecycles")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(Equal(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+				"docker":               "docker_app_lifecycle/docker_app_lifecycle.tgz",
+			}))
+		})
+
+		It("reads a YAML list", func() {
+			lifecycles, err := flags.LifecyclesFromConfig([]byte(`
+lifecycles:
+- buildpack/cflinuxfs4:buildpack_app_lifecycle/buildpack_app_lifecycle.tgz
+- cnb/cflinuxfs4:cnb_app_lifecycle/cnb_app_lifecycle.tgz
+`), "lifecycles")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(Equal(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+				"cnb/cflinuxfs4":       "cnb_app_lifecycle/cnb_app_lifecycle.tgz",
+			}))
+		})
+
+		It("yields nothing without the section", func() {
+			lifecycles, err := flags.LifecyclesFromConfig([]byte(`{"other": 1}`), "lifecycles")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(BeEmpty())
+		})
+
+		It("reports duplicates", func() {
+			_, err := flags.LifecyclesFromConfig([]byte(`{"lifecycles": {"docker": "a.tgz", "docker": "a.tgz"}}`), "lifecycles")
+			Expect(errors.Is(err, flags.ErrLifecycleDuplicate)).To(BeTrue())
+			Expect(err).To(MatchError("Invalid lifecycle value: duplicate lifecycle 'docker'"))
+		})
+
+		It("reports conflicting paths", func() {
+			_, err := flags.LifecyclesFromConfig([]byte("lifecycles: [docker:a.tgz, docker:b.tgz]"), "lifecycles")
+			Expect(errors.Is(err, flags.ErrLifecyclePathConflict)).To(BeTrue())
+			Expect(errors.Is(err, flags.ErrLifecycleDuplicate)).To(BeFalse())
+		})
+
+		It("reports malformed entries", func() {
+			_, err := flags.LifecyclesFromConfig([]byte(`{"lifecycles": {"docker": ""}}`), "lifecycles")
+			Expect(err).To(Equal(flags.ErrLifecyclePathEmpty))
+
+			_, err = flags.LifecyclesFromConfig([]byte(`{"lifecycles": "docker:a.tgz"}`), "lifecycles")
+			Expect(err).To(Equal(flags.ErrLifecycleConfigInvalid))
+
+			_, err = flags.LifecyclesFromConfig([]byte(`{"lifecycles": [{"docker": "a.tgz"}]}`), "lifecycles")
+			Expect(err).To(Equal(flags.ErrLifecycleConfigInvalid))
+		})
+	})
+
+	Describe("LifecyclesFromEnv", func() {
+		It("reads comma and whitespace separated entries", func() {
+			lifecycles, err := flags.LifecyclesFromEnv("buildpack/cflinuxfs4:bp.tgz, docker:docker.tgz\ncnb/cflinuxfs4:cnb.tgz")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(Equal(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "bp.tgz",
+				"docker":               "docker.tgz",
+				"cnb/cflinuxfs4":       "cnb.tgz",
+			}))
+		})
+
+		It("reports malformed entries", func() {
+			_, err := flags.LifecyclesFromEnv("docker")
+			Expect(err).To(Equal(flags.ErrLifecycleFormatInvalid))
+
+			_, err = flags.LifecyclesFromEnv("docker:a.tgz,docker:b.tgz")
+			Expect(errors.Is(err, flags.ErrLifecyclePathConflict)).To(BeTrue())
+		})
+	})
+
+	Describe("Load", func() {
+		var configFile string
+
+		BeforeEach(func() {
+			configFile = filepath.Join(GinkgoT().TempDir(), "config.yml")
+			Expect(os.WriteFile(configFile, []byte(`
+log_level: info
+lifecycles:
+  buildpack/cflinuxfs4: config-bp.tgz
+  docker: config-docker.tgz
+  cnb/cflinuxfs4: config-cnb.tgz
+`), 0644)).To(Succeed())
+
+			GinkgoT().Setenv("TEST_LIFECYCLES", "docker:env-docker.tgz,cnb/cflinuxfs4:env-cnb.tgz")
+		})
+
+		It("merges config, environment and flags in order of precedence", func() {
+			lifecycles, err := flags.LifecycleLoader{
+				ConfigFile: configFile,
+				EnvVar:     "TEST_LIFECYCLES",
+				Flags:      flags.LifecycleMap{"cnb/cflinuxfs4": "flag-cnb.tgz"},
+			}.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(Equal(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "config-bp.tgz",
+				"docker":               "env-docker.tgz",
+				"cnb/cflinuxfs4":       "flag-cnb.tgz",
+			}))
+		})
+
+		It("prefers flags over the environment and the environment over config", func() {
+			GinkgoT().Setenv("TEST_LIFECYCLES", "docker:env-docker.tgz")
+			loader := flags.LifecycleLoader{ConfigFile: configFile, EnvVar: "TEST_LIFECYCLES"}
+
+			lifecycles, err := loader.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(HaveKeyWithValue("docker", "env-docker.tgz"))
+
+			loader.Flags = flags.LifecycleMap{"docker": "flag-docker.tgz"}
+			lifecycles, err = loader.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(HaveKeyWithValue("docker", "flag-docker.tgz"))
+		})
+
+		It("reads the default environment variable when none is named", func() {
+			GinkgoT().Setenv(flags.DefaultLifecycleEnvVar, "docker:default-env-docker.tgz")
+
+			lifecycles, err := flags.LifecycleLoader{ConfigFile: configFile}.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycles).To(Equal(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "config-bp.tgz",
+				"docker":               "default-env-docker.tgz",
+				"cnb/cflinuxfs4":       "config-cnb.tgz",
+			}))
+		})
+
+		It("fails on a missing config file", func() {
+			_, err := flags.LifecycleLoader{ConfigFile: filepath.Join(GinkgoT().TempDir(), "missing.yml")}.Load()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	LifecycleChecksumAlgorithm = "sha256"
+	DefaultLifecycleURLTimeout = time.Minute
+)
+
+var ErrLifecycleVerificationFailed = newLifecycleError("could not verify path for lifecycle")
+
+// LifecycleEntry is a lifecycle in a LifecycleMap, with its name split by the
+// "lifecycle/stack" convention. Lifecycles without a stack, such as docker,
+// have an empty Stack. The checksum is only set on verified entries.
+type LifecycleEntry struct {
+	Name              string
+	Lifecycle         string
+	Stack             string
+	Path              string
+	ChecksumAlgorithm string
+	ChecksumValue     string
+}
+
+func ParseLifecycleName(name string) (lifecycle, stack string) {
+	lifecycle, stack, _ = strings.Cut(name, "/")
+	return lifecycle, stack
+}
+
+// Entries returns the lifecycles sorted by name.
+func (s LifecycleMap) Entries() []LifecycleEntry {
+	entries := make([]LifecycleEntry, 0, len(s))
+	for name, path := range s {
+		lifecycle, stack := ParseLifecycleName(name)
+		entries = append(entries, LifecycleEntry{
+			Name:      name,
+			Lifecycle: lifecycle,
+			Stack:     stack,
+			Path:      path,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// URLChecker opens the lifecycle blob at a URL. It should fail when the blob
+// does not exist.
+type URLChecker interface {
+	Open(url string) (io.ReadCloser, error)
+}
+
+// HTTPURLChecker downloads blobs with Client, or with a client that gives up
+// after DefaultLifecycleURLTimeout when Client is nil.
+type HTTPURLChecker struct {
+	Client *http.Client
+}
+
+var defaultLifecycleHTTPClient = &http.Client{Timeout: DefaultLifecycleURLTimeout}
+
+func (c HTTPURLChecker) Open(url string) (io.ReadCloser, error) {
+	client := c.Client
+	if client == nil {
+		client = defaultLifecycleHTTPClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// LifecycleVerifier checks that every lifecycle path resolves to a blob and
+// computes its checksum. Paths with a URL scheme are opened with URLChecker,
+// which defaults to HTTPURLChecker. Other paths are files relative to
+// StaticDirectory, the directory the file server serves lifecycles from.
+type LifecycleVerifier struct {
+	StaticDirectory string
+	URLChecker      URLChecker
+}
+
+func (v LifecycleVerifier) Verify(lifecycles LifecycleMap) ([]LifecycleEntry, error) {
+	entries := lifecycles.Entries()
+	for i := range entries {
+		checksum, err := v.checksum(entries[i].Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", namedLifecycleError(ErrLifecycleVerificationFailed, entries[i].Name), err)
+		}
+
+		entries[i].ChecksumAlgorithm = LifecycleChecksumAlgorithm
+		entries[i].ChecksumValue = checksum
+	}
+	return entries, nil
+}
+
+func (v LifecycleVerifier) checksum(path string) (string, error) {
+	blob, err := v.open(path)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, blob)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (v LifecycleVerifier) open(path string) (io.ReadCloser, error) {
+	parsed, err := url.Parse(path)
+	if err == nil && parsed.Scheme != "" {
+		checker := v.URLChecker
+		if checker == nil {
+			checker = HTTPURLChecker{}
+		}
+		return checker.Open(path)
+	}
+
+	return os.Open(filepath.Join(v.StaticDirectory, filepath.FromSlash(path)))
+}
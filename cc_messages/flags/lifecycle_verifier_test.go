@@ -0,0 +1,115 @@
+package flags_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+type fakeURLChecker map[string]string
+
+func (c fakeURLChecker) Open(url string) (io.ReadCloser, error) {
+	blob, ok := c[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(strings.NewReader(blob)), nil
+}
+
+var _ = Describe("LifecycleVerifier", func() {
+	// sha256 of "lifecycle"
+	const lifecycleChecksum = "f31168c67a1482e74cb97ec041650a193c18a4bb0847d656aa70707e72cd4e9d"
+
+	var (
+		staticDir string
+		verifier  flags.LifecycleVerifier
+		checker   fakeURLChecker
+	)
+
+	BeforeEach(func() {
+		staticDir = GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(staticDir, "buildpack_app_lifecycle"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(staticDir, "buildpack_app_lifecycle", "buildpack_app_lifecycle.tgz"), []byte("lifecycle"), 0644)).To(Succeed())
+
+		checker = fakeURLChecker{"https://blobs.example.com/docker_app_lifecycle.tgz": "lifecycle"}
+		verifier = flags.LifecycleVerifier{StaticDirectory: staticDir, URLChecker: checker}
+	})
+
+	Describe("Entries", func() {
+		It("splits names into lifecycle and stack", func() {
+			lifecycles := flags.LifecycleMap{
+				"docker":               "docker.tgz",
+				"buildpack/cflinuxfs4": "bp.tgz",
+			}
+			Expect(lifecycles.Entries()).To(Equal([]flags.LifecycleEntry{
+				{Name: "buildpack/cflinuxfs4", Lifecycle: "buildpack", Stack: "cflinuxfs4", Path: "bp.tgz"},
+				{Name: "docker", Lifecycle: "docker", Path: "docker.tgz"},
+			}))
+		})
+	})
+
+	Describe("Verify", func() {
+		It("checksums local files and URLs", func() {
+			entries, err := verifier.Verify(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+				"docker":               "https://blobs.example.com/docker_app_lifecycle.tgz",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			for _, entry := range entries {
+				Expect(entry.ChecksumAlgorithm).To(Equal("sha256"))
+				Expect(entry.ChecksumValue).To(Equal(lifecycleChecksum))
+			}
+		})
+
+		It("fails on a missing file", func() {
+			_, err := verifier.Verify(flags.LifecycleMap{
+				"buildpack/cflinuxfs4": "buildpack_app_lifecycle/lifecycle.tgz",
+			})
+			Expect(errors.Is(err, flags.ErrLifecycleVerificationFailed)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("'buildpack/cflinuxfs4'")))
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+
+		It("fails on a missing URL", func() {
+			_, err := verifier.Verify(flags.LifecycleMap{
+				"docker": "https://blobs.example.com/missing.tgz",
+			})
+			Expect(errors.Is(err, flags.ErrLifecycleVerificationFailed)).To(BeTrue())
+		})
+	})
+
+	Describe("HTTPURLChecker", func() {
+		var server *ghttp.Server
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			server.RouteToHandler("GET", "/lifecycle.tgz", ghttp.RespondWith(http.StatusOK, "lifecycle"))
+			server.RouteToHandler("GET", "/missing.tgz", ghttp.RespondWith(http.StatusNotFound, nil))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("verifies blobs served over HTTP", func() {
+			verifier = flags.LifecycleVerifier{}
+			entries, err := verifier.Verify(flags.LifecycleMap{"cnb/cflinuxfs4": server.URL() + "/lifecycle.tgz"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].ChecksumValue).To(Equal(lifecycleChecksum))
+
+			_, err = verifier.Verify(flags.LifecycleMap{"cnb/cflinuxfs4": server.URL() + "/missing.tgz"})
+			Expect(errors.Is(err, flags.ErrLifecycleVerificationFailed)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("unexpected status 404")))
+		})
+	})
+})
@@ -1,18 +1,18 @@
 package flags
 
-import (
-	"fmt"
-	"strings"
-)
+import "fmt"
 
 var (
 	ErrLifecycleFormatInvalid = newLifecycleError("not of the form 'lifecycle-name:path'")
 	ErrLifecycleNameEmpty     = newLifecycleError("empty lifecycle name")
 	ErrLifecyclePathEmpty     = newLifecycleError("empty path")
+	ErrLifecycleDuplicate     = newLifecycleError("duplicate lifecycle")
+	ErrLifecyclePathConflict  = newLifecycleError("conflicting paths for lifecycle")
 )
 
 type lifecycleError struct {
-	msg string
+	msg       string
+	lifecycle string
 }
 
 func newLifecycleError(msg string) error {
@@ -20,29 +20,36 @@ func newLifecycleError(msg string) error {
 }
 
 func (e lifecycleError) Error() string {
+	if e.lifecycle != "" {
+		return "Invalid lifecycle value: " + e.msg + " '" + e.lifecycle + "'"
+	}
 	return "Invalid lifecycle value: " + e.msg
 }
 
+// Is matches the sentinel errors regardless of the lifecycle they name.
+func (e lifecycleError) Is(target error) bool {
+	t, ok := target.(lifecycleError)
+	return ok && t.msg == e.msg
+}
+
+func namedLifecycleError(sentinel error, lifecycle string) error {
+	e := sentinel.(lifecycleError)
+	e.lifecycle = lifecycle
+	return e
+}
+
 type LifecycleMap map[string]string
 
 func (s *LifecycleMap) String() string {
 	return fmt.Sprintf("%v", *s)
 }
 
-func (s *LifecycleMap) Set(value string) error {
-	parts := strings.SplitN(value, ":", 2)
-	if len(parts) != 2 {
-		return ErrLifecycleFormatInvalid
-	}
-
-	if parts[0] == "" {
-		return ErrLifecycleNameEmpty
-	}
-
-	if parts[1] == "" {
-		return ErrLifecyclePathEmpty
-	}
+var lifecycleErrors = keyValueErrors{
+	formatInvalid: ErrLifecycleFormatInvalid,
+	keyEmpty:      ErrLifecycleNameEmpty,
+	valueEmpty:    ErrLifecyclePathEmpty,
+}
 
-	(*s)[parts[0]] = parts[1]
-	return nil
+func (s *LifecycleMap) Set(value string) error {
+	return setKeyValue(*s, value, lifecycleErrors, nil, nil)
 }
@@ -0,0 +1,50 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrPlacementTagFormatInvalid = newPlacementTagError("not of the form 'isolation-segment:placement-tag'")
+	ErrIsolationSegmentEmpty     = newPlacementTagError("empty isolation segment")
+	ErrPlacementTagEmpty         = newPlacementTagError("empty placement tag")
+	ErrPlacementTagInvalid       = newPlacementTagError("placement tag contains whitespace or ':'")
+)
+
+type placementTagError struct {
+	msg string
+}
+
+func newPlacementTagError(msg string) error {
+	return placementTagError{msg: msg}
+}
+
+func (e placementTagError) Error() string {
+	return "Invalid placement tag value: " + e.msg
+}
+
+var placementTagErrors = keyValueErrors{
+	formatInvalid: ErrPlacementTagFormatInvalid,
+	keyEmpty:      ErrIsolationSegmentEmpty,
+	valueEmpty:    ErrPlacementTagEmpty,
+}
+
+// PlacementTagMap maps isolation segment names to the placement tag of the
+// cells that host them.
+type PlacementTagMap map[string]string
+
+func (s *PlacementTagMap) String() string {
+	return fmt.Sprintf("%v", *s)
+}
+
+func (s *PlacementTagMap) Set(value string) error {
+	return setKeyValue(*s, value, placementTagErrors, nil, validPlacementTag)
+}
+
+func validPlacementTag(value string) error {
+	if strings.ContainsAny(value, ": \t\n") {
+		return ErrPlacementTagInvalid
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package flags_test
+
+import (
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PlacementTagMap", func() {
+	var placementTags flags.PlacementTagMap
+
+	BeforeEach(func() {
+		placementTags = flags.PlacementTagMap{}
+	})
+
+	It("adds the mapping", func() {
+		Expect(placementTags.Set("isolated:gpu-cells")).To(Succeed())
+		Expect(placementTags).To(Equal(flags.PlacementTagMap{"isolated": "gpu-cells"}))
+	})
+
+	DescribeTable("rejects malformed values",
+		func(value string, expected error) {
+			Expect(placementTags.Set(value)).To(Equal(expected))
+		},
+		Entry("no separator", "isolated", flags.ErrPlacementTagFormatInvalid),
+		Entry("empty isolation segment", ":gpu-cells", flags.ErrIsolationSegmentEmpty),
+		Entry("empty placement tag", "isolated:", flags.ErrPlacementTagEmpty),
+		Entry("whitespace", "isolated:gpu cells", flags.ErrPlacementTagInvalid),
+		Entry("extra separator", "isolated:gpu:cells", flags.ErrPlacementTagInvalid),
+	)
+})
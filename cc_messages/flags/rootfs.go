@@ -0,0 +1,52 @@
+package flags
+
+import (
+	"fmt"
+	"net/url"
+)
+
+var (
+	ErrRootFSFormatInvalid = newRootFSError("not of the form 'stack:rootfs-url'")
+	ErrRootFSStackEmpty    = newRootFSError("empty stack")
+	ErrRootFSURLEmpty      = newRootFSError("empty rootfs url")
+	ErrRootFSURLInvalid    = newRootFSError("invalid rootfs url")
+)
+
+type rootFSError struct {
+	msg string
+}
+
+func newRootFSError(msg string) error {
+	return rootFSError{msg: msg}
+}
+
+func (e rootFSError) Error() string {
+	return "Invalid rootfs value: " + e.msg
+}
+
+var rootFSErrors = keyValueErrors{
+	formatInvalid: ErrRootFSFormatInvalid,
+	keyEmpty:      ErrRootFSStackEmpty,
+	valueEmpty:    ErrRootFSURLEmpty,
+}
+
+// RootFSMap maps stack names to the rootfs they run on.
+type RootFSMap map[string]string
+
+func (s *RootFSMap) String() string {
+	return fmt.Sprintf("%v", *s)
+}
+
+func (s *RootFSMap) Set(value string) error {
+	return setKeyValue(*s, value, rootFSErrors, nil, validRootFSURL)
+}
+
+// validRootFSURL requires a scheme, such as "preloaded:" or "docker://", as
+// url.Parse alone accepts almost any string.
+func validRootFSURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return ErrRootFSURLInvalid
+	}
+	return nil
+}
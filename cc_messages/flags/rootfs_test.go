@@ -0,0 +1,39 @@
+package flags_test
+
+import (
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RootFSMap", func() {
+	var rootFSes flags.RootFSMap
+
+	BeforeEach(func() {
+		rootFSes = flags.RootFSMap{}
+	})
+
+	It("adds the mapping", func() {
+		Expect(rootFSes.Set("cflinuxfs4:preloaded:cflinuxfs4")).To(Succeed())
+		Expect(rootFSes.Set("windows:oci:///C:/var/vcap/packages/windows")).To(Succeed())
+		Expect(rootFSes.Set("app:docker:///cloudfoundry/app")).To(Succeed())
+		Expect(rootFSes).To(Equal(flags.RootFSMap{
+			"cflinuxfs4": "preloaded:cflinuxfs4",
+			"windows":    "oci:///C:/var/vcap/packages/windows",
+			"app":        "docker:///cloudfoundry/app",
+		}))
+	})
+
+	DescribeTable("rejects malformed values",
+		func(value string, expected error) {
+			Expect(rootFSes.Set(value)).To(Equal(expected))
+		},
+		Entry("no separator", "cflinuxfs4", flags.ErrRootFSFormatInvalid),
+		Entry("empty stack", ":/rootfs.tar", flags.ErrRootFSStackEmpty),
+		Entry("empty url", "cflinuxfs4:", flags.ErrRootFSURLEmpty),
+		Entry("invalid url", "cflinuxfs4:%zz", flags.ErrRootFSURLInvalid),
+		Entry("url without scheme", "cflinuxfs4:garbage", flags.ErrRootFSURLInvalid),
+		Entry("bare path", "cflinuxfs4:/var/vcap/packages/cflinuxfs4/rootfs.tar", flags.ErrRootFSURLInvalid),
+	)
+})
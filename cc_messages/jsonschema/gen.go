@@ -0,0 +1,16 @@
+//go:build ignore
+
+package main
+
+import (
+	"log"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages/jsonschema"
+)
+
+func main() {
+	err := jsonschema.WriteAll("schemas")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,250 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Generate builds the schema for the JSON encoding of the given value's type.
+// A property is required when encoding/json always emits it, that is when its
+// tag does not carry the omitempty option.
+func Generate(value interface{}) (*Schema, error) {
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	g := &generator{defs: map[string]*Schema{}}
+	schema, err := g.schemaFor(t, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/$defs/")
+		schema = g.defs[name]
+		delete(g.defs, name)
+	}
+
+	schema.Schema = Draft
+	schema.Title = t.Name()
+	if len(g.defs) > 0 {
+		schema.Defs = g.defs
+	}
+	return schema, nil
+}
+
+func Marshal(value interface{}) ([]byte, error) {
+	schema, err := Generate(value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(encoded, '\n'), nil
+}
+
+func FileName(message Message) string {
+	return message.Name + ".json"
+}
+
+func WriteAll(dir string) error {
+	for _, message := range Messages {
+		encoded, err := Marshal(message.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %s", message.Name, err)
+		}
+
+		err = os.WriteFile(filepath.Join(dir, FileName(message)), encoded, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type generator struct {
+	defs map[string]*Schema
+}
+
+func (g *generator) schemaFor(t reflect.Type, nullable bool) (*Schema, error) {
+	switch {
+	case t == timeType:
+		return withNull(&Schema{Type: "string", Format: "date-time"}, nullable), nil
+	case t == rawJSONType:
+		return &Schema{}, nil
+	case t.Kind() != reflect.Ptr && t.Implements(marshalerType):
+		if isInteger(t.Kind()) {
+			return withNull(&Schema{Type: "string"}, nullable), nil
+		}
+		return &Schema{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schemaFor(t.Elem(), true)
+	case reflect.String:
+		return withNull(&Schema{Type: "string"}, nullable), nil
+	case reflect.Bool:
+		return withNull(&Schema{Type: "boolean"}, nullable), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return withNull(&Schema{Type: "integer"}, nullable), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := int64(0)
+		return withNull(&Schema{Type: "integer", Minimum: &zero}, nullable), nil
+	case reflect.Float32, reflect.Float64:
+		return withNull(&Schema{Type: "number"}, nullable), nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaFor(t.Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		return withNull(&Schema{Type: "array", Items: items}, t.Kind() == reflect.Slice), nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.schemaFor(t.Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		return withNull(&Schema{Type: "object", AdditionalProperties: values}, true), nil
+	case reflect.Struct:
+		return g.structRef(t, nullable)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func (g *generator) structRef(t reflect.Type, nullable bool) (*Schema, error) {
+	name := defName(t)
+	if _, ok := g.defs[name]; !ok {
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		g.defs[name] = schema
+
+		err := g.addFields(schema, t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return withNull(&Schema{Ref: "#/$defs/" + name}, nullable), nil
+}
+
+func (g *generator) addFields(schema *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				err := g.addFields(schema, embedded)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := g.schemaFor(field.Type, false)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %s", t.Name(), field.Name, err)
+		}
+		schema.Properties[name] = property
+
+		if !hasOption(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return nil
+}
+
+func defName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return pkg + "." + t.Name()
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func withNull(schema *Schema, nullable bool) *Schema {
+	if !nullable {
+		return schema
+	}
+
+	if schema.Ref != "" {
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	}
+
+	if typeName, ok := schema.Type.(string); ok {
+		schema.Type = []string{typeName, "null"}
+	}
+	return schema
+}
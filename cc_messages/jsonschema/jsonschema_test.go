@@ -0,0 +1,77 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/jsonschema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON Schema", func() {
+	It("matches the checked in schemas (run `go generate` after changing a message)", func() {
+		for _, message := range jsonschema.Messages {
+			generated, err := jsonschema.Marshal(message.Value)
+			Expect(err).NotTo(HaveOccurred())
+
+			checkedIn, err := os.ReadFile(filepath.Join("schemas", jsonschema.FileName(message)))
+			Expect(err).NotTo(HaveOccurred(), message.Name)
+			Expect(string(generated)).To(Equal(string(checkedIn)), message.Name)
+		}
+	})
+
+	It("has no stale schemas", func() {
+		expected := []string{}
+		for _, message := range jsonschema.Messages {
+			expected = append(expected, jsonschema.FileName(message))
+		}
+
+		entries, err := os.ReadDir("schemas")
+		Expect(err).NotTo(HaveOccurred())
+
+		files := []string{}
+		for _, entry := range entries {
+			files = append(files, entry.Name())
+		}
+		Expect(files).To(ConsistOf(expected))
+	})
+
+	Describe("Generate", func() {
+		var schema *jsonschema.Schema
+
+		BeforeEach(func() {
+			var err error
+			schema, err = jsonschema.Generate(cc_messages.TaskRequestFromCC{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("names the schema after the message", func() {
+			Expect(schema.Schema).To(Equal(jsonschema.Draft))
+			Expect(schema.Title).To(Equal("TaskRequestFromCC"))
+			Expect(schema.Type).To(Equal("object"))
+		})
+
+		It("requires the fields that are always encoded", func() {
+			Expect(schema.Required).To(ContainElements("task_guid", "command", "memory_mb"))
+			Expect(schema.Required).NotTo(ContainElements("docker_user", "isolation_segment"))
+		})
+
+		It("reflects misspelled omitempty options as required", func() {
+			Expect(schema.Required).To(ContainElement("log_source"))
+		})
+
+		It("references nested types through $defs", func() {
+			encoded, err := json.Marshal(schema.Properties["environment"])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(encoded).To(MatchJSON(`{
+				"type": ["array", "null"],
+				"items": {"anyOf": [{"$ref": "#/$defs/models.EnvironmentVariable"}, {"type": "null"}]}
+			}`))
+			Expect(schema.Defs).To(HaveKey("models.EnvironmentVariable"))
+		})
+	})
+})
@@ -0,0 +1,38 @@
+package jsonschema
+
+import "code.cloudfoundry.org/runtimeschema/cc_messages"
+
+type Message struct {
+	Name  string
+	Value interface{}
+}
+
+var Messages = []Message{
+	{"DesireAppRequestFromCC", cc_messages.DesireAppRequestFromCC{}},
+	{"CCDesiredStateServerResponse", cc_messages.CCDesiredStateServerResponse{}},
+	{"CCDesiredStateFingerprintResponse", cc_messages.CCDesiredStateFingerprintResponse{}},
+	{"CCTaskStatesResponse", cc_messages.CCTaskStatesResponse{}},
+	{"CCDesiredAppFingerprint", cc_messages.CCDesiredAppFingerprint{}},
+	{"CCTaskState", cc_messages.CCTaskState{}},
+	{"CCBulkToken", cc_messages.CCBulkToken{}},
+	{"CCHTTPRoute", cc_messages.CCHTTPRoute{}},
+	{"CCTCPRoute", cc_messages.CCTCPRoute{}},
+	{"TaskRequestFromCC", cc_messages.TaskRequestFromCC{}},
+	{"TaskFailResponseForCC", cc_messages.TaskFailResponseForCC{}},
+	{"TaskError", cc_messages.TaskError{}},
+	{"StagingRequestFromCC", cc_messages.StagingRequestFromCC{}},
+	{"BuildpackStagingData", cc_messages.BuildpackStagingData{}},
+	{"DockerStagingData", cc_messages.DockerStagingData{}},
+	{"CNBStagingData", cc_messages.CNBStagingData{}},
+	{"BuildpackStagingResult", cc_messages.BuildpackStagingResult{}},
+	{"DockerStagingResult", cc_messages.DockerStagingResult{}},
+	{"CNBStagingResult", cc_messages.CNBStagingResult{}},
+	{"StagingResponseForCC", cc_messages.StagingResponseForCC{}},
+	{"StagingError", cc_messages.StagingError{}},
+	{"StagingTaskAnnotation", cc_messages.StagingTaskAnnotation{}},
+	{"AppCrashedRequest", cc_messages.AppCrashedRequest{}},
+	{"AppReschedulingRequest", cc_messages.AppReschedulingRequest{}},
+	{"AppReadinessChangedRequest", cc_messages.AppReadinessChangedRequest{}},
+	{"LRPInstance", cc_messages.LRPInstance{}},
+	{"LRPInstanceStats", cc_messages.LRPInstanceStats{}},
+}
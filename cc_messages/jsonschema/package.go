@@ -0,0 +1,3 @@
+package jsonschema // import "code.cloudfoundry.org/runtimeschema/cc_messages/jsonschema"
+
+//go:generate go run gen.go
@@ -0,0 +1,263 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+var (
+	ErrLifecycleDataMissing = errors.New("lifecycle data missing")
+	ErrLifecycleNameEmpty   = errors.New("lifecycle name empty")
+	ErrLifecycleDecoderNil  = errors.New("lifecycle decoder nil")
+)
+
+type ErrLifecycleNotRegistered struct {
+	Lifecycle string
+}
+
+func (err ErrLifecycleNotRegistered) Error() string {
+	return fmt.Sprintf("no lifecycle registered for %q", err.Lifecycle)
+}
+
+type ErrLifecycleAlreadyRegistered struct {
+	Lifecycle string
+}
+
+func (err ErrLifecycleAlreadyRegistered) Error() string {
+	return fmt.Sprintf("lifecycle %q is already registered", err.Lifecycle)
+}
+
+type LifecycleDataDecoder func(data json.RawMessage) (interface{}, error)
+
+type LifecycleDataValidator func(data interface{}) error
+
+type lifecycleRegistration struct {
+	decode   LifecycleDataDecoder
+	validate LifecycleDataValidator
+}
+
+type LifecycleRegistry struct {
+	lock       sync.RWMutex
+	lifecycles map[string]lifecycleRegistration
+}
+
+var DefaultLifecycleRegistry = NewLifecycleRegistry()
+
+func init() {
+	DefaultLifecycleRegistry.MustRegister(BuildpackLifecycleName, decodeBuildpackStagingData, nil)
+	DefaultLifecycleRegistry.MustRegister(DockerLifecycleName, decodeDockerStagingData, nil)
+	DefaultLifecycleRegistry.MustRegister(CNBLifecycleName, decodeCNBStagingData, nil)
+}
+
+func NewLifecycleRegistry() *LifecycleRegistry {
+	return &LifecycleRegistry{
+		lifecycles: map[string]lifecycleRegistration{},
+	}
+}
+
+// Register adds a lifecycle to the registry. When validator is nil, decoded
+// values that implement models.Validator are validated with their own
+// Validate method.
+func (r *LifecycleRegistry) Register(lifecycle string, decoder LifecycleDataDecoder, validator LifecycleDataValidator) error {
+	if lifecycle == "" {
+		return ErrLifecycleNameEmpty
+	}
+
+	if decoder == nil {
+		return ErrLifecycleDecoderNil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.lifecycles[lifecycle]; ok {
+		return ErrLifecycleAlreadyRegistered{Lifecycle: lifecycle}
+	}
+
+	r.lifecycles[lifecycle] = lifecycleRegistration{decode: decoder, validate: validator}
+	return nil
+}
+
+func (r *LifecycleRegistry) MustRegister(lifecycle string, decoder LifecycleDataDecoder, validator LifecycleDataValidator) {
+	err := r.Register(lifecycle, decoder, validator)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (r *LifecycleRegistry) Lifecycles() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	lifecycles := make([]string, 0, len(r.lifecycles))
+	for lifecycle := range r.lifecycles {
+		lifecycles = append(lifecycles, lifecycle)
+	}
+	sort.Strings(lifecycles)
+	return lifecycles
+}
+
+func (r *LifecycleRegistry) Decode(lifecycle string, data *json.RawMessage) (interface{}, error) {
+	r.lock.RLock()
+	registration, ok := r.lifecycles[lifecycle]
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, ErrLifecycleNotRegistered{Lifecycle: lifecycle}
+	}
+
+	if data == nil {
+		return nil, ErrLifecycleDataMissing
+	}
+
+	decoded, err := registration.decode(*data)
+	if err != nil {
+		return nil, err
+	}
+
+	if registration.validate != nil {
+		err = registration.validate(decoded)
+	} else if validator, ok := decoded.(models.Validator); ok {
+		err = validator.Validate()
+	}
+	if err != nil {
+		return nil, prefixInvalidFields("lifecycle_data", err)
+	}
+
+	return decoded, nil
+}
+
+func RegisterLifecycle(lifecycle string, decoder LifecycleDataDecoder, validator LifecycleDataValidator) error {
+	return DefaultLifecycleRegistry.Register(lifecycle, decoder, validator)
+}
+
+func (stagingRequest StagingRequestFromCC) DecodeLifecycleData() (interface{}, error) {
+	return DefaultLifecycleRegistry.Decode(stagingRequest.Lifecycle, stagingRequest.LifecycleData)
+}
+
+func (stagingData BuildpackStagingData) Validate() error {
+	var validationError models.ValidationError
+
+	if stagingData.AppBitsDownloadUri == "" {
+		validationError = validationError.Append(invalidField("app_bits_download_uri", "must not be empty"))
+	}
+
+	if stagingData.BuildArtifactsCacheUploadUri == "" {
+		validationError = validationError.Append(invalidField("build_artifacts_cache_upload_uri", "must not be empty"))
+	}
+
+	if stagingData.DropletUploadUri == "" {
+		validationError = validationError.Append(invalidField("droplet_upload_uri", "must not be empty"))
+	}
+
+	if stagingData.Stack == "" {
+		validationError = validationError.Append(invalidField("stack", "must not be empty"))
+	}
+
+	for i, buildpack := range stagingData.Buildpacks {
+		if buildpack.Url == "" {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("buildpacks[%d].url", i), "must not be empty"))
+		}
+	}
+
+	return validationError.ToError()
+}
+
+func (stagingData DockerStagingData) Validate() error {
+	var validationError models.ValidationError
+
+	if stagingData.DockerImageUrl == "" {
+		validationError = validationError.Append(invalidField("docker_image", "must not be empty"))
+	}
+
+	if (stagingData.DockerUser == "") != (stagingData.DockerPassword == "") {
+		validationError = validationError.Append(invalidField("docker_user", "docker_user and docker_password must be given together"))
+	}
+
+	return validationError.ToError()
+}
+
+func (stagingData CNBStagingData) Validate() error {
+	var validationError models.ValidationError
+
+	if stagingData.AppBitsDownloadUri == "" {
+		validationError = validationError.Append(invalidField("app_bits_download_uri", "must not be empty"))
+	}
+
+	if stagingData.DropletUploadUri == "" {
+		validationError = validationError.Append(invalidField("droplet_upload_uri", "must not be empty"))
+	}
+
+	if stagingData.Stack == "" {
+		validationError = validationError.Append(invalidField("stack", "must not be empty"))
+	}
+
+	if len(stagingData.Buildpacks) == 0 && !stagingData.AutoDetect {
+		validationError = validationError.Append(invalidField("buildpacks", "required unless auto_detect is set"))
+	}
+
+	for i, buildpack := range stagingData.Buildpacks {
+		if buildpack.Url == "" {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("buildpacks[%d].url", i), "must not be empty"))
+		}
+	}
+
+	for i, credentials := range stagingData.Credentials {
+		if credentials.Registry == "" {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("credentials[%d].registry", i), "must not be empty"))
+		}
+		if credentials.Username == "" {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("credentials[%d].username", i), "must not be empty"))
+		}
+	}
+
+	return validationError.ToError()
+}
+
+func decodeBuildpackStagingData(data json.RawMessage) (interface{}, error) {
+	var stagingData BuildpackStagingData
+	err := json.Unmarshal(data, &stagingData)
+	if err != nil {
+		return nil, err
+	}
+	return stagingData, nil
+}
+
+func decodeDockerStagingData(data json.RawMessage) (interface{}, error) {
+	var stagingData DockerStagingData
+	err := json.Unmarshal(data, &stagingData)
+	if err != nil {
+		return nil, err
+	}
+	return stagingData, nil
+}
+
+func decodeCNBStagingData(data json.RawMessage) (interface{}, error) {
+	var stagingData CNBStagingData
+	err := json.Unmarshal(data, &stagingData)
+	if err != nil {
+		return nil, err
+	}
+	return stagingData, nil
+}
+
+func prefixInvalidFields(prefix string, err error) error {
+	validationError, ok := err.(models.ValidationError)
+	if !ok {
+		if fieldErr, ok := err.(ErrInvalidField); ok {
+			return invalidField(prefix+"."+fieldErr.Field, fieldErr.Reason)
+		}
+		return err
+	}
+
+	prefixed := make(models.ValidationError, 0, len(validationError))
+	for _, e := range validationError {
+		prefixed = append(prefixed, prefixInvalidFields(prefix, e))
+	}
+	return prefixed
+}
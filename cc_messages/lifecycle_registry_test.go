@@ -0,0 +1,163 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type kpackStagingData struct {
+	Image string `json:"image"`
+}
+
+var _ = Describe("LifecycleRegistry", func() {
+	rawMessage := func(payload string) *json.RawMessage {
+		raw := json.RawMessage(payload)
+		return &raw
+	}
+
+	Describe("DefaultLifecycleRegistry", func() {
+		It("knows the buildpack, cnb and docker lifecycles", func() {
+			Expect(cc_messages.DefaultLifecycleRegistry.Lifecycles()).To(Equal([]string{"buildpack", "cnb", "docker"}))
+		})
+	})
+
+	Describe("StagingRequestFromCC.DecodeLifecycleData", func() {
+		var stagingRequest cc_messages.StagingRequestFromCC
+
+		BeforeEach(func() {
+			stagingRequest = cc_messages.StagingRequestFromCC{
+				AppId:     "app-id",
+				Lifecycle: "buildpack",
+				LifecycleData: rawMessage(`{
+					"app_bits_download_uri": "http://app-bits",
+					"build_artifacts_cache_upload_uri": "http://cache-upload",
+					"droplet_upload_uri": "http://droplet-upload",
+					"buildpacks": [{"name": "ruby", "key": "ruby-key", "url": "http://ruby.zip"}],
+					"stack": "cflinuxfs4"
+				}`),
+			}
+		})
+
+		It("decodes buildpack lifecycle data", func() {
+			lifecycleData, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycleData).To(Equal(cc_messages.BuildpackStagingData{
+				AppBitsDownloadUri:           "http://app-bits",
+				BuildArtifactsCacheUploadUri: "http://cache-upload",
+				DropletUploadUri:             "http://droplet-upload",
+				Buildpacks:                   []cc_messages.Buildpack{{Name: "ruby", Key: "ruby-key", Url: "http://ruby.zip"}},
+				Stack:                        "cflinuxfs4",
+			}))
+		})
+
+		It("decodes docker lifecycle data", func() {
+			stagingRequest.Lifecycle = "docker"
+			stagingRequest.LifecycleData = rawMessage(`{"docker_image": "cloudfoundry/diego-docker-app"}`)
+
+			lifecycleData, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycleData).To(Equal(cc_messages.DockerStagingData{DockerImageUrl: "cloudfoundry/diego-docker-app"}))
+		})
+
+		It("decodes cnb lifecycle data", func() {
+			stagingRequest.Lifecycle = "cnb"
+			stagingRequest.LifecycleData = rawMessage(`{
+				"app_bits_download_uri": "http://app-bits",
+				"droplet_upload_uri": "http://droplet-upload",
+				"stack": "cflinuxfs4",
+				"auto_detect": true
+			}`)
+
+			lifecycleData, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lifecycleData).To(Equal(cc_messages.CNBStagingData{
+				AppBitsDownloadUri: "http://app-bits",
+				DropletUploadUri:   "http://droplet-upload",
+				Stack:              "cflinuxfs4",
+				AutoDetect:         true,
+			}))
+		})
+
+		It("reports validation errors under lifecycle_data", func() {
+			stagingRequest.LifecycleData = rawMessage(`{"buildpacks": [{"name": "ruby"}], "stack": "cflinuxfs4"}`)
+
+			_, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).To(BeAssignableToTypeOf(models.ValidationError{}))
+
+			fields := []string{}
+			for _, e := range err.(models.ValidationError) {
+				fields = append(fields, e.(cc_messages.ErrInvalidField).Field)
+			}
+			Expect(fields).To(ConsistOf(
+				"lifecycle_data.app_bits_download_uri",
+				"lifecycle_data.build_artifacts_cache_upload_uri",
+				"lifecycle_data.droplet_upload_uri",
+				"lifecycle_data.buildpacks[0].url",
+			))
+		})
+
+		It("errors when the lifecycle data is missing", func() {
+			stagingRequest.LifecycleData = nil
+			_, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).To(Equal(cc_messages.ErrLifecycleDataMissing))
+		})
+
+		It("errors when the lifecycle data is malformed", func() {
+			stagingRequest.LifecycleData = rawMessage(`{"stack": 7}`)
+			_, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("errors when the lifecycle is not registered", func() {
+			stagingRequest.Lifecycle = "kpack"
+			_, err := stagingRequest.DecodeLifecycleData()
+			Expect(err).To(Equal(cc_messages.ErrLifecycleNotRegistered{Lifecycle: "kpack"}))
+		})
+	})
+
+	Describe("Register", func() {
+		var registry *cc_messages.LifecycleRegistry
+
+		decodeKpack := func(data json.RawMessage) (interface{}, error) {
+			var stagingData kpackStagingData
+			err := json.Unmarshal(data, &stagingData)
+			return stagingData, err
+		}
+
+		BeforeEach(func() {
+			registry = cc_messages.NewLifecycleRegistry()
+		})
+
+		It("decodes and validates new lifecycles", func() {
+			validationErr := errors.New("image required")
+			err := registry.Register("kpack", decodeKpack, func(data interface{}) error {
+				if data.(kpackStagingData).Image == "" {
+					return validationErr
+				}
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(registry.Decode("kpack", rawMessage(`{"image": "registry/app"}`))).To(Equal(kpackStagingData{Image: "registry/app"}))
+
+			_, err = registry.Decode("kpack", rawMessage(`{}`))
+			Expect(err).To(Equal(validationErr))
+		})
+
+		It("rejects duplicate registrations", func() {
+			Expect(registry.Register("kpack", decodeKpack, nil)).To(Succeed())
+			Expect(registry.Register("kpack", decodeKpack, nil)).To(Equal(cc_messages.ErrLifecycleAlreadyRegistered{Lifecycle: "kpack"}))
+		})
+
+		It("rejects empty names and nil decoders", func() {
+			Expect(registry.Register("", decodeKpack, nil)).To(Equal(cc_messages.ErrLifecycleNameEmpty))
+			Expect(registry.Register("kpack", nil, nil)).To(Equal(cc_messages.ErrLifecycleDecoderNil))
+		})
+	})
+})
@@ -1,7 +1,11 @@
 package cc_messages
 
-import "time"
-import "code.cloudfoundry.org/bbs/models"
+import (
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+)
 
 type LRPInstanceState string
 
@@ -32,4 +36,140 @@ type LRPInstanceStats struct {
 	CpuPercentage float64   `json:"cpu"`
 	MemoryBytes   uint64    `json:"mem"`
 	DiskBytes     uint64    `json:"disk"`
+
+	// Optional values, left nil when the cell does not report them.
+	MemoryQuotaBytes            *uint64  `json:"mem_quota,omitempty"`
+	DiskQuotaBytes              *uint64  `json:"disk_quota,omitempty"`
+	CpuEntitlementPercentage    *float64 `json:"cpu_entitlement,omitempty"`
+	AbsoluteCpuUsageNanos       *uint64  `json:"absolute_cpu_usage,omitempty"`
+	AbsoluteCpuEntitlementNanos *uint64  `json:"absolute_cpu_entitlement,omitempty"`
+	LogRateBytesPerSecond       *uint64  `json:"log_rate,omitempty"`
+	LogRateLimitBytesPerSecond  *int64   `json:"log_rate_limit,omitempty"`
+	ContainerAgeNanos           *uint64  `json:"container_age,omitempty"`
+	NetworkRxBytes              *uint64  `json:"rx_bytes,omitempty"`
+	NetworkTxBytes              *uint64  `json:"tx_bytes,omitempty"`
+}
+
+func LRPInstanceStateFor(actual *models.ActualLRP) LRPInstanceState {
+	switch actual.State {
+	case models.ActualLRPStateUnclaimed:
+		if actual.PlacementError != "" {
+			return LRPInstanceStateDown
+		}
+		return LRPInstanceStateStarting
+	case models.ActualLRPStateClaimed:
+		return LRPInstanceStateStarting
+	case models.ActualLRPStateRunning:
+		return LRPInstanceStateRunning
+	case models.ActualLRPStateCrashed:
+		return LRPInstanceStateCrashed
+	default:
+		return LRPInstanceStateUnknown
+	}
+}
+
+// NewLRPInstances reports one instance per index of the process, sorted by
+// index. When BBS holds several actual LRPs for an index (an evacuating or
+// suspect instance alongside its replacement) the one most likely to be
+// serving traffic is reported. Indices below numInstances without an actual
+// LRP are reported as DOWN. stats is keyed by instance index and may be nil.
+func NewLRPInstances(processGuid string, numInstances int, actualLRPs []*models.ActualLRP, stats map[int]*LRPInstanceStats, now time.Time) []LRPInstance {
+	byIndex := map[int]*models.ActualLRP{}
+	for _, actual := range actualLRPs {
+		index := int(actual.Index)
+		if current, ok := byIndex[index]; !ok || actualLRPRank(actual) < actualLRPRank(current) {
+			byIndex[index] = actual
+		}
+	}
+
+	for index := 0; index < numInstances; index++ {
+		if _, ok := byIndex[index]; !ok {
+			byIndex[index] = nil
+		}
+	}
+
+	instances := make([]LRPInstance, 0, len(byIndex))
+	for index, actual := range byIndex {
+		var instance LRPInstance
+		if actual == nil {
+			instance = LRPInstance{
+				ProcessGuid: processGuid,
+				Index:       uint(index),
+				State:       LRPInstanceStateDown,
+				Since:       now.Unix(),
+			}
+		} else {
+			instance = newLRPInstance(actual, now)
+		}
+
+		instance.Stats = stats[index]
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Index < instances[j].Index
+	})
+	return instances
+}
+
+func newLRPInstance(actual *models.ActualLRP, now time.Time) LRPInstance {
+	instance := LRPInstance{
+		ProcessGuid:  actual.ProcessGuid,
+		InstanceGuid: actual.InstanceGuid,
+		Index:        uint(actual.Index),
+		State:        LRPInstanceStateFor(actual),
+		Details:      actual.PlacementError,
+		Host:         actual.Address,
+		NetInfo:      actual.ActualLRPNetInfo,
+		Since:        actual.Since / int64(time.Second),
+		Uptime:       (now.UnixNano() - actual.Since) / int64(time.Second),
+	}
+
+	if actual.State == models.ActualLRPStateCrashed {
+		instance.Details = actual.CrashReason
+	}
+
+	for _, mapping := range actual.Ports {
+		if mapping.ContainerPort == DefaultPort {
+			instance.Port = uint16(mapping.HostPort)
+		}
+	}
+	if instance.Port == 0 && len(actual.Ports) > 0 {
+		instance.Port = uint16(actual.Ports[0].HostPort)
+	}
+
+	return instance
+}
+
+// actualLRPRank orders actual LRPs sharing an index, lowest first: running
+// instances win over everything else, then ordinary instances over suspect
+// and evacuating ones.
+func actualLRPRank(actual *models.ActualLRP) int {
+	switch actual.State {
+	case models.ActualLRPStateRunning:
+		switch actual.Presence {
+		case models.ActualLRP_Ordinary:
+			return 0
+		case models.ActualLRP_Suspect:
+			return 1
+		default:
+			return 2
+		}
+	case models.ActualLRPStateCrashed:
+		if actual.Presence == models.ActualLRP_Ordinary {
+			return 3
+		}
+	case models.ActualLRPStateClaimed:
+		switch actual.Presence {
+		case models.ActualLRP_Ordinary:
+			return 4
+		case models.ActualLRP_Suspect:
+			return 5
+		}
+	}
+
+	if actual.Presence == models.ActualLRP_Evacuating {
+		return 6
+	}
+	return 7
 }
@@ -0,0 +1,126 @@
+package cc_messages_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LRPInstance", func() {
+	var (
+		now   time.Time
+		since int64
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(1257894100, 0)
+		since = time.Unix(1257894000, 0).UnixNano()
+	})
+
+	actualLRP := func(index int32, instanceGuid, state string, presence models.ActualLRP_Presence) *models.ActualLRP {
+		return &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", index, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+			ActualLRPNetInfo: models.NewActualLRPNetInfo(
+				"1.2.3.4", "10.0.0.1", models.ActualLRPNetInfo_PreferredAddressHost,
+				models.NewPortMapping(61001, 2222), models.NewPortMapping(61000, 8080),
+			),
+			State:    state,
+			Presence: presence,
+			Since:    since,
+		}
+	}
+
+	Describe("LRPInstanceStateFor", func() {
+		DescribeTable("maps actual LRP states",
+			func(state, placementError string, expected cc_messages.LRPInstanceState) {
+				actual := &models.ActualLRP{State: state, PlacementError: placementError}
+				Expect(cc_messages.LRPInstanceStateFor(actual)).To(Equal(expected))
+			},
+			Entry("unclaimed", models.ActualLRPStateUnclaimed, "", cc_messages.LRPInstanceStateStarting),
+			Entry("unclaimed and unplaceable", models.ActualLRPStateUnclaimed, "insufficient resources", cc_messages.LRPInstanceStateDown),
+			Entry("claimed", models.ActualLRPStateClaimed, "", cc_messages.LRPInstanceStateStarting),
+			Entry("running", models.ActualLRPStateRunning, "", cc_messages.LRPInstanceStateRunning),
+			Entry("crashed", models.ActualLRPStateCrashed, "", cc_messages.LRPInstanceStateCrashed),
+			Entry("unknown", "BOGUS", "", cc_messages.LRPInstanceStateUnknown),
+		)
+	})
+
+	Describe("NewLRPInstances", func() {
+		It("fills in the instance details from the actual LRP", func() {
+			stats := &cc_messages.LRPInstanceStats{Time: now, CpuPercentage: 0.5, MemoryBytes: 1024, DiskBytes: 2048}
+			actual := actualLRP(0, "instance-guid", models.ActualLRPStateRunning, models.ActualLRP_Ordinary)
+
+			instances := cc_messages.NewLRPInstances("process-guid", 1, []*models.ActualLRP{actual}, map[int]*cc_messages.LRPInstanceStats{0: stats}, now)
+			Expect(instances).To(Equal([]cc_messages.LRPInstance{{
+				ProcessGuid:  "process-guid",
+				InstanceGuid: "instance-guid",
+				Index:        0,
+				State:        cc_messages.LRPInstanceStateRunning,
+				Host:         "1.2.3.4",
+				Port:         61000,
+				NetInfo:      actual.ActualLRPNetInfo,
+				Uptime:       100,
+				Since:        1257894000,
+				Stats:        stats,
+			}}))
+		})
+
+		It("sorts by index and reports missing indices as down", func() {
+			instances := cc_messages.NewLRPInstances("process-guid", 4, []*models.ActualLRP{
+				actualLRP(3, "instance-3", models.ActualLRPStateRunning, models.ActualLRP_Ordinary),
+				actualLRP(1, "instance-1", models.ActualLRPStateClaimed, models.ActualLRP_Ordinary),
+				actualLRP(5, "instance-5", models.ActualLRPStateRunning, models.ActualLRP_Ordinary),
+			}, nil, now)
+
+			Expect(instances).To(HaveLen(5))
+			Expect(instances[0]).To(Equal(cc_messages.LRPInstance{ProcessGuid: "process-guid", Index: 0, State: cc_messages.LRPInstanceStateDown, Since: now.Unix()}))
+			Expect(instances[1].State).To(Equal(cc_messages.LRPInstanceStateStarting))
+			Expect(instances[2].State).To(Equal(cc_messages.LRPInstanceStateDown))
+			Expect(instances[3].InstanceGuid).To(Equal("instance-3"))
+			Expect(instances[4].InstanceGuid).To(Equal("instance-5"))
+		})
+
+		It("reports the crash reason of crashed instances", func() {
+			crashed := actualLRP(0, "instance-guid", models.ActualLRPStateCrashed, models.ActualLRP_Ordinary)
+			crashed.CrashReason = "APP/PROC/WEB: Exited with status 1"
+
+			instances := cc_messages.NewLRPInstances("process-guid", 1, []*models.ActualLRP{crashed}, nil, now)
+			Expect(instances[0].Details).To(Equal("APP/PROC/WEB: Exited with status 1"))
+		})
+
+		DescribeTable("resolves several actual LRPs for an index",
+			func(first, second *models.ActualLRP, expectedInstanceGuid string) {
+				for _, actualLRPs := range [][]*models.ActualLRP{{first, second}, {second, first}} {
+					instances := cc_messages.NewLRPInstances("process-guid", 1, actualLRPs, nil, now)
+					Expect(instances).To(HaveLen(1))
+					Expect(instances[0].InstanceGuid).To(Equal(expectedInstanceGuid))
+				}
+			},
+			Entry("evacuating while the replacement starts",
+				actualLRP(0, "evacuating", models.ActualLRPStateRunning, models.ActualLRP_Evacuating),
+				actualLRP(0, "replacement", models.ActualLRPStateClaimed, models.ActualLRP_Ordinary),
+				"evacuating",
+			),
+			Entry("evacuating once the replacement runs",
+				actualLRP(0, "evacuating", models.ActualLRPStateRunning, models.ActualLRP_Evacuating),
+				actualLRP(0, "replacement", models.ActualLRPStateRunning, models.ActualLRP_Ordinary),
+				"replacement",
+			),
+			Entry("suspect while the replacement starts",
+				actualLRP(0, "suspect", models.ActualLRPStateRunning, models.ActualLRP_Suspect),
+				actualLRP(0, "replacement", models.ActualLRPStateUnclaimed, models.ActualLRP_Ordinary),
+				"suspect",
+			),
+			Entry("crashed replacement of an evacuating instance",
+				actualLRP(0, "evacuating", models.ActualLRPStateRunning, models.ActualLRP_Evacuating),
+				actualLRP(0, "replacement", models.ActualLRPStateCrashed, models.ActualLRP_Ordinary),
+				"evacuating",
+			),
+		)
+	})
+})
@@ -0,0 +1,369 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const RedactedValue = "REDACTED"
+
+var DefaultSensitiveNamePatterns = []string{
+	"(?i)password",
+	"(?i)passwd",
+	"(?i)secret",
+	"(?i)token",
+	"(?i)credential",
+	"(?i)private_?key",
+	"(?i)api_?key",
+	"(?i)signature",
+}
+
+var DefaultRedactor = MustNewRedactor(DefaultSensitiveNamePatterns)
+
+type Redactor struct {
+	sensitiveNames []*regexp.Regexp
+}
+
+func NewRedactor(sensitiveNamePatterns []string) (*Redactor, error) {
+	sensitiveNames := make([]*regexp.Regexp, 0, len(sensitiveNamePatterns))
+	for _, pattern := range sensitiveNamePatterns {
+		sensitiveName, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		sensitiveNames = append(sensitiveNames, sensitiveName)
+	}
+
+	return &Redactor{sensitiveNames: sensitiveNames}, nil
+}
+
+func MustNewRedactor(sensitiveNamePatterns []string) *Redactor {
+	redactor, err := NewRedactor(sensitiveNamePatterns)
+	if err != nil {
+		panic(err)
+	}
+	return redactor
+}
+
+func (r *Redactor) IsSensitive(name string) bool {
+	for _, sensitiveName := range r.sensitiveNames {
+		if sensitiveName.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) DesireAppRequest(desireAppMessage DesireAppRequestFromCC) DesireAppRequestFromCC {
+	desireAppMessage.DropletUri = r.URI(desireAppMessage.DropletUri)
+	desireAppMessage.DockerPassword = redactValue(desireAppMessage.DockerPassword)
+	desireAppMessage.Environment = r.Environment(desireAppMessage.Environment)
+	desireAppMessage.VolumeMounts = r.volumeMounts(desireAppMessage.VolumeMounts)
+	return desireAppMessage
+}
+
+func (r *Redactor) TaskRequest(taskRequest TaskRequestFromCC) TaskRequestFromCC {
+	taskRequest.DropletUri = r.URI(taskRequest.DropletUri)
+	taskRequest.DockerPassword = redactValue(taskRequest.DockerPassword)
+	taskRequest.CompletionCallbackUrl = r.URI(taskRequest.CompletionCallbackUrl)
+	taskRequest.EnvironmentVariables = r.Environment(taskRequest.EnvironmentVariables)
+	taskRequest.VolumeMounts = r.volumeMounts(taskRequest.VolumeMounts)
+	return taskRequest
+}
+
+func (r *Redactor) StagingRequest(stagingRequest StagingRequestFromCC) StagingRequestFromCC {
+	stagingRequest.Environment = r.Environment(stagingRequest.Environment)
+	stagingRequest.CompletionCallback = r.URI(stagingRequest.CompletionCallback)
+	stagingRequest.LifecycleData = r.rawJSON(stagingRequest.LifecycleData)
+	return stagingRequest
+}
+
+func (r *Redactor) BuildpackStagingData(stagingData BuildpackStagingData) BuildpackStagingData {
+	stagingData.AppBitsDownloadUri = r.URI(stagingData.AppBitsDownloadUri)
+	stagingData.BuildArtifactsCacheDownloadUri = r.URI(stagingData.BuildArtifactsCacheDownloadUri)
+	stagingData.BuildArtifactsCacheUploadUri = r.URI(stagingData.BuildArtifactsCacheUploadUri)
+	stagingData.DropletUploadUri = r.URI(stagingData.DropletUploadUri)
+
+	if stagingData.Buildpacks != nil {
+		buildpacks := make([]Buildpack, len(stagingData.Buildpacks))
+		for i, buildpack := range stagingData.Buildpacks {
+			buildpack.Url = r.URI(buildpack.Url)
+			buildpacks[i] = buildpack
+		}
+		stagingData.Buildpacks = buildpacks
+	}
+
+	return stagingData
+}
+
+func (r *Redactor) DockerStagingData(stagingData DockerStagingData) DockerStagingData {
+	stagingData.DockerPassword = redactValue(stagingData.DockerPassword)
+	return stagingData
+}
+
+func (r *Redactor) CNBStagingData(stagingData CNBStagingData) CNBStagingData {
+	stagingData.AppBitsDownloadUri = r.URI(stagingData.AppBitsDownloadUri)
+	stagingData.BuildArtifactsCacheDownloadUri = r.URI(stagingData.BuildArtifactsCacheDownloadUri)
+	stagingData.BuildArtifactsCacheUploadUri = r.URI(stagingData.BuildArtifactsCacheUploadUri)
+	stagingData.DropletUploadUri = r.URI(stagingData.DropletUploadUri)
+
+	if stagingData.Buildpacks != nil {
+		buildpacks := make([]CNBBuildpack, len(stagingData.Buildpacks))
+		for i, buildpack := range stagingData.Buildpacks {
+			buildpack.Url = r.URI(buildpack.Url)
+			buildpacks[i] = buildpack
+		}
+		stagingData.Buildpacks = buildpacks
+	}
+
+	if stagingData.Credentials != nil {
+		credentials := make([]CNBRegistryCredentials, len(stagingData.Credentials))
+		for i, credential := range stagingData.Credentials {
+			credential.Password = redactValue(credential.Password)
+			credentials[i] = credential
+		}
+		stagingData.Credentials = credentials
+	}
+
+	return stagingData
+}
+
+func (r *Redactor) Environment(env []*models.EnvironmentVariable) []*models.EnvironmentVariable {
+	if env == nil {
+		return nil
+	}
+
+	redacted := make([]*models.EnvironmentVariable, len(env))
+	for i, envVar := range env {
+		if envVar == nil {
+			continue
+		}
+		value := r.jsonString(envVar.Value)
+		if r.IsSensitive(envVar.Name) {
+			value = redactValue(envVar.Value)
+		}
+		redacted[i] = &models.EnvironmentVariable{Name: envVar.Name, Value: value}
+	}
+	return redacted
+}
+
+// URI masks the password embedded in a URI, or the user when it carries no
+// password (as with token-style credentials), and the values of query
+// parameters with sensitive names such as signed URL signatures.
+func (r *Redactor) URI(uri string) string {
+	if uri == "" {
+		return uri
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Opaque != "" {
+		return uri
+	}
+
+	changed := false
+	if parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), RedactedValue)
+		} else {
+			parsed.User = url.User(RedactedValue)
+		}
+		changed = true
+	}
+
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for key, values := range query {
+			if r.IsSensitive(key) {
+				for i := range values {
+					values[i] = RedactedValue
+				}
+				changed = true
+			}
+		}
+		if changed {
+			parsed.RawQuery = query.Encode()
+		}
+	}
+
+	if !changed {
+		return uri
+	}
+	return parsed.String()
+}
+
+func (r *Redactor) volumeMounts(volumeMounts []*VolumeMount) []*VolumeMount {
+	if volumeMounts == nil {
+		return nil
+	}
+
+	redacted := make([]*VolumeMount, len(volumeMounts))
+	for i, volumeMount := range volumeMounts {
+		if volumeMount == nil {
+			continue
+		}
+		mount := *volumeMount
+		if mount.Device.MountConfig != nil {
+			mount.Device.MountConfig = r.jsonValue(mount.Device.MountConfig).(map[string]interface{})
+		}
+		redacted[i] = &mount
+	}
+	return redacted
+}
+
+func (r *Redactor) rawJSON(payload *json.RawMessage) *json.RawMessage {
+	if payload == nil {
+		return nil
+	}
+
+	var decoded interface{}
+	err := json.Unmarshal(*payload, &decoded)
+	if err != nil {
+		return payload
+	}
+
+	encoded, err := json.Marshal(r.jsonValue(decoded))
+	if err != nil {
+		return payload
+	}
+
+	redacted := json.RawMessage(encoded)
+	return &redacted
+}
+
+// jsonString redacts values such as VCAP_SERVICES that hold JSON documents
+// with credentials nested inside them.
+func (r *Redactor) jsonString(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return r.URI(value)
+	}
+
+	var decoded interface{}
+	err := json.Unmarshal([]byte(value), &decoded)
+	if err != nil {
+		return value
+	}
+
+	encoded, err := json.Marshal(r.jsonValue(decoded))
+	if err != nil {
+		return value
+	}
+	return string(encoded)
+}
+
+func (r *Redactor) jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			if r.IsSensitive(key) {
+				redacted[key] = RedactedValue
+			} else {
+				redacted[key] = r.jsonValue(nested)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, nested := range v {
+			redacted[i] = r.jsonValue(nested)
+		}
+		return redacted
+	case string:
+		return r.URI(v)
+	default:
+		return v
+	}
+}
+
+func redactValue(value string) string {
+	if value == "" {
+		return value
+	}
+	return RedactedValue
+}
+
+type desireAppRequestFromCC DesireAppRequestFromCC
+type taskRequestFromCC TaskRequestFromCC
+type stagingRequestFromCC StagingRequestFromCC
+type buildpackStagingData BuildpackStagingData
+type dockerStagingData DockerStagingData
+type cnbStagingData CNBStagingData
+
+func (desireAppMessage DesireAppRequestFromCC) Redacted() DesireAppRequestFromCC {
+	return DefaultRedactor.DesireAppRequest(desireAppMessage)
+}
+
+func (desireAppMessage DesireAppRequestFromCC) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), desireAppRequestFromCC(desireAppMessage.Redacted()))
+}
+
+func (desireAppMessage DesireAppRequestFromCC) LagerData() lager.Data {
+	return lager.Data{"desire_app_request": desireAppRequestFromCC(desireAppMessage.Redacted())}
+}
+
+func (taskRequest TaskRequestFromCC) Redacted() TaskRequestFromCC {
+	return DefaultRedactor.TaskRequest(taskRequest)
+}
+
+func (taskRequest TaskRequestFromCC) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), taskRequestFromCC(taskRequest.Redacted()))
+}
+
+func (taskRequest TaskRequestFromCC) LagerData() lager.Data {
+	return lager.Data{"task_request": taskRequestFromCC(taskRequest.Redacted())}
+}
+
+func (stagingRequest StagingRequestFromCC) Redacted() StagingRequestFromCC {
+	return DefaultRedactor.StagingRequest(stagingRequest)
+}
+
+func (stagingRequest StagingRequestFromCC) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), stagingRequestFromCC(stagingRequest.Redacted()))
+}
+
+func (stagingRequest StagingRequestFromCC) LagerData() lager.Data {
+	return lager.Data{"staging_request": stagingRequestFromCC(stagingRequest.Redacted())}
+}
+
+func (stagingData BuildpackStagingData) Redacted() BuildpackStagingData {
+	return DefaultRedactor.BuildpackStagingData(stagingData)
+}
+
+func (stagingData BuildpackStagingData) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), buildpackStagingData(stagingData.Redacted()))
+}
+
+func (stagingData BuildpackStagingData) LagerData() lager.Data {
+	return lager.Data{"buildpack_staging_data": buildpackStagingData(stagingData.Redacted())}
+}
+
+func (stagingData DockerStagingData) Redacted() DockerStagingData {
+	return DefaultRedactor.DockerStagingData(stagingData)
+}
+
+func (stagingData DockerStagingData) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), dockerStagingData(stagingData.Redacted()))
+}
+
+func (stagingData DockerStagingData) LagerData() lager.Data {
+	return lager.Data{"docker_staging_data": dockerStagingData(stagingData.Redacted())}
+}
+
+func (stagingData CNBStagingData) Redacted() CNBStagingData {
+	return DefaultRedactor.CNBStagingData(stagingData)
+}
+
+func (stagingData CNBStagingData) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), cnbStagingData(stagingData.Redacted()))
+}
+
+func (stagingData CNBStagingData) LagerData() lager.Data {
+	return lager.Data{"cnb_staging_data": cnbStagingData(stagingData.Redacted())}
+}
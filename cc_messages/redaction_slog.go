@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package cc_messages
+
+import "log/slog"
+
+// log/slog needs Go 1.21 while the module still builds with Go 1.20, so the
+// slog.LogValuer implementations only exist on toolchains that have it.
+
+func (desireAppMessage DesireAppRequestFromCC) LogValue() slog.Value {
+	return slog.AnyValue(desireAppRequestFromCC(desireAppMessage.Redacted()))
+}
+
+func (taskRequest TaskRequestFromCC) LogValue() slog.Value {
+	return slog.AnyValue(taskRequestFromCC(taskRequest.Redacted()))
+}
+
+func (stagingRequest StagingRequestFromCC) LogValue() slog.Value {
+	return slog.AnyValue(stagingRequestFromCC(stagingRequest.Redacted()))
+}
+
+func (stagingData BuildpackStagingData) LogValue() slog.Value {
+	return slog.AnyValue(buildpackStagingData(stagingData.Redacted()))
+}
+
+func (stagingData DockerStagingData) LogValue() slog.Value {
+	return slog.AnyValue(dockerStagingData(stagingData.Redacted()))
+}
+
+func (stagingData CNBStagingData) LogValue() slog.Value {
+	return slog.AnyValue(cnbStagingData(stagingData.Redacted()))
+}
@@ -0,0 +1,77 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// Exit codes of the buildpack lifecycle builder.
+const (
+	BuildpackDetectFailedExitCode  = 222
+	BuildpackCompileFailedExitCode = 223
+	BuildpackReleaseFailedExitCode = 224
+)
+
+const StagingFailedMessage = "staging failed"
+
+// NewStagingError classifies the failure reason of a staging task for the
+// given lifecycle. Buildpack exit codes are only recognised for the buildpack
+// lifecycle. Reasons that are not recognised are replaced by a generic
+// message, as they may carry details of the cell that should not reach the
+// user.
+func NewStagingError(lifecycle, failureReason string) *StagingError {
+	lifecycleName, _, _ := strings.Cut(lifecycle, "/")
+	if status, ok := ParseExitStatus(failureReason); ok && lifecycleName == BuildpackLifecycleName {
+		switch status {
+		case BuildpackDetectFailedExitCode:
+			return &StagingError{Id: BUILDPACK_DETECT_FAILED, Message: failureReason}
+		case BuildpackCompileFailedExitCode:
+			return &StagingError{Id: BUILDPACK_COMPILE_FAILED, Message: failureReason}
+		case BuildpackReleaseFailedExitCode:
+			return &StagingError{Id: BUILDPACK_RELEASE_FAILED, Message: failureReason}
+		}
+	}
+
+	switch TaskErrorIDForFailureReason(failureReason) {
+	case TASK_INSUFFICIENT_RESOURCES:
+		return &StagingError{Id: INSUFFICIENT_RESOURCES, Message: failureReason}
+	case TASK_NO_COMPATIBLE_CELL:
+		return &StagingError{Id: NO_COMPATIBLE_CELL, Message: failureReason}
+	case TASK_CELL_COMMUNICATION_ERROR:
+		return &StagingError{Id: CELL_COMMUNICATION_ERROR, Message: failureReason}
+	}
+
+	return &StagingError{Id: STAGING_ERROR, Message: StagingFailedMessage}
+}
+
+// StagingResponseForTaskCallback takes the lifecycle from the staging task
+// annotation. A missing or invalid annotation only loses the classification
+// of lifecycle specific failures.
+func StagingResponseForTaskCallback(response *models.TaskCallbackResponse) (StagingResponseForCC, error) {
+	annotation, _ := DecodeStagingTaskAnnotation(response.Annotation)
+	return newStagingResponse(annotation.Lifecycle, response.Failed, response.FailureReason, response.Result)
+}
+
+func StagingResponseForTask(task *models.Task) (StagingResponseForCC, error) {
+	annotation, _ := StagingTaskAnnotationFromTask(task)
+	return newStagingResponse(annotation.Lifecycle, task.Failed, task.FailureReason, task.Result)
+}
+
+func newStagingResponse(lifecycle string, failed bool, failureReason, result string) (StagingResponseForCC, error) {
+	if failed {
+		return StagingResponseForCC{Error: NewStagingError(lifecycle, failureReason)}, nil
+	}
+
+	result = strings.TrimSpace(result)
+	if result == "" {
+		return StagingResponseForCC{}, ErrStagingResultMissing
+	}
+	if !json.Valid([]byte(result)) {
+		return StagingResponseForCC{}, ErrStagingResultInvalid
+	}
+
+	raw := json.RawMessage(result)
+	return StagingResponseForCC{Result: &raw}, nil
+}
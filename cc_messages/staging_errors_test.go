@@ -0,0 +1,138 @@
+package cc_messages_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Staging errors", func() {
+	DescribeTable("NewStagingError",
+		func(failureReason string, expectedId cc_messages.StagingErrorID, expectedMessage string) {
+			Expect(cc_messages.NewStagingError("buildpack/cflinuxfs4", failureReason)).To(Equal(&cc_messages.StagingError{
+				Id:      expectedId,
+				Message: expectedMessage,
+			}))
+		},
+		Entry("detect failed", "Exited with status 222", cc_messages.BUILDPACK_DETECT_FAILED, "Exited with status 222"),
+		Entry("detect failed with prefix", "STG: Exited with status 222", cc_messages.BUILDPACK_DETECT_FAILED, "STG: Exited with status 222"),
+		Entry("compile failed", "Exited with status 223", cc_messages.BUILDPACK_COMPILE_FAILED, "Exited with status 223"),
+		Entry("release failed", "Exited with status 224", cc_messages.BUILDPACK_RELEASE_FAILED, "Exited with status 224"),
+		Entry("insufficient memory", "insufficient resources: memory", cc_messages.INSUFFICIENT_RESOURCES, "insufficient resources: memory"),
+		Entry("insufficient disk and memory", "insufficient resources: disk, memory", cc_messages.INSUFFICIENT_RESOURCES, "insufficient resources: disk, memory"),
+		Entry("no compatible cell", "found no compatible cell", cc_messages.NO_COMPATIBLE_CELL, "found no compatible cell"),
+		Entry("no cell in isolation segment", `found no compatible cell with placement tags "isolated"`, cc_messages.NO_COMPATIBLE_CELL, `found no compatible cell with placement tags "isolated"`),
+		Entry("cells unreachable", "unable to communicate to compatible cells", cc_messages.CELL_COMMUNICATION_ERROR, "unable to communicate to compatible cells"),
+		Entry("other exit status", "Exited with status 1", cc_messages.STAGING_ERROR, "staging failed"),
+		Entry("out of memory", "Exited with status 137 (out of memory)", cc_messages.STAGING_ERROR, "staging failed"),
+		Entry("download failure", "Downloading App Package failed: Get https://10.0.16.4:9023/internal/v4/package: dial tcp 10.0.16.4:9023: i/o timeout", cc_messages.STAGING_ERROR, "staging failed"),
+		Entry("stream in failure", "Copying into the container failed: stream-in: nstar: error streaming in: exit status 2", cc_messages.STAGING_ERROR, "staging failed"),
+		Entry("container creation", "failed to create container: container-guid: mount: no space left on device", cc_messages.STAGING_ERROR, "staging failed"),
+		Entry("cancelled", "task was cancelled", cc_messages.STAGING_ERROR, "staging failed"),
+		Entry("empty", "", cc_messages.STAGING_ERROR, "staging failed"),
+	)
+
+	DescribeTable("buildpack exit codes for other lifecycles",
+		func(lifecycle string) {
+			Expect(cc_messages.NewStagingError(lifecycle, "Exited with status 222")).To(Equal(&cc_messages.StagingError{
+				Id:      cc_messages.STAGING_ERROR,
+				Message: "staging failed",
+			}))
+		},
+		Entry("docker", "docker"),
+		Entry("cnb", "cnb/cflinuxfs4"),
+		Entry("unknown", ""),
+	)
+
+	Describe("StagingResponseForTaskCallback", func() {
+		var buildpackAnnotation, dockerAnnotation string
+
+		BeforeEach(func() {
+			var err error
+			buildpackAnnotation, err = cc_messages.StagingTaskAnnotation{Lifecycle: "buildpack"}.Encode()
+			Expect(err).NotTo(HaveOccurred())
+			dockerAnnotation, err = cc_messages.StagingTaskAnnotation{Lifecycle: "docker"}.Encode()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("wraps the result of a successful task", func() {
+			response, err := cc_messages.StagingResponseForTaskCallback(&models.TaskCallbackResponse{
+				TaskGuid: "staging-guid",
+				Result:   `{"lifecycle_type":"buildpack"}` + "\n",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error).To(BeNil())
+			Expect(string(*response.Result)).To(Equal(`{"lifecycle_type":"buildpack"}`))
+		})
+
+		DescribeTable("rejects a successful task without a usable result",
+			func(result string, expectedErr error) {
+				_, err := cc_messages.StagingResponseForTaskCallback(&models.TaskCallbackResponse{
+					TaskGuid: "staging-guid",
+					Result:   result,
+				})
+				Expect(err).To(Equal(expectedErr))
+			},
+			Entry("empty", "", cc_messages.ErrStagingResultMissing),
+			Entry("whitespace", " \n", cc_messages.ErrStagingResultMissing),
+			Entry("not JSON", `{"lifecycle_type":`, cc_messages.ErrStagingResultInvalid),
+		)
+
+		It("classifies the failure of a failed buildpack task", func() {
+			response, err := cc_messages.StagingResponseForTaskCallback(&models.TaskCallbackResponse{
+				TaskGuid:      "staging-guid",
+				Failed:        true,
+				FailureReason: "Exited with status 223",
+				Annotation:    buildpackAnnotation,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Result).To(BeNil())
+			Expect(response.Error).To(Equal(&cc_messages.StagingError{Id: cc_messages.BUILDPACK_COMPILE_FAILED, Message: "Exited with status 223"}))
+		})
+
+		It("does not apply buildpack exit codes to other lifecycles", func() {
+			response, err := cc_messages.StagingResponseForTaskCallback(&models.TaskCallbackResponse{
+				TaskGuid:      "staging-guid",
+				Failed:        true,
+				FailureReason: "Exited with status 222",
+				Annotation:    dockerAnnotation,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error.Id).To(Equal(cc_messages.STAGING_ERROR))
+		})
+	})
+
+	Describe("StagingResponseForTask", func() {
+		It("classifies the failure of a failed task", func() {
+			response, err := cc_messages.StagingResponseForTask(&models.Task{
+				TaskGuid:      "staging-guid",
+				State:         models.Task_Completed,
+				Failed:        true,
+				FailureReason: "found no compatible cell",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error.Id).To(Equal(cc_messages.NO_COMPATIBLE_CELL))
+		})
+
+		It("takes the lifecycle from the task annotation", func() {
+			task := &models.Task{
+				TaskGuid:       "staging-guid",
+				TaskDefinition: &models.TaskDefinition{},
+				Failed:         true,
+				FailureReason:  "Exited with status 222",
+			}
+			Expect(cc_messages.StagingTaskAnnotation{Lifecycle: "buildpack"}.AttachTo(task.TaskDefinition)).To(Succeed())
+
+			response, err := cc_messages.StagingResponseForTask(task)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error.Id).To(Equal(cc_messages.BUILDPACK_DETECT_FAILED))
+		})
+
+		It("rejects an empty result", func() {
+			_, err := cc_messages.StagingResponseForTask(&models.Task{TaskGuid: "staging-guid", Result: ""})
+			Expect(err).To(Equal(cc_messages.ErrStagingResultMissing))
+		})
+	})
+})
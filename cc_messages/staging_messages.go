@@ -55,6 +55,31 @@ type DockerStagingData struct {
 	DockerEmail       string `json:"docker_email,omitempty"`
 }
 
+type CNBStagingData struct {
+	AppBitsDownloadUri             string                   `json:"app_bits_download_uri"`
+	BuildArtifactsCacheDownloadUri string                   `json:"build_artifacts_cache_download_uri,omitempty"`
+	BuildArtifactsCacheUploadUri   string                   `json:"build_artifacts_cache_upload_uri"`
+	Buildpacks                     []CNBBuildpack           `json:"buildpacks"`
+	Credentials                    []CNBRegistryCredentials `json:"credentials,omitempty"`
+	DropletUploadUri               string                   `json:"droplet_upload_uri"`
+	Stack                          string                   `json:"stack"`
+	RunImage                       string                   `json:"run_image,omitempty"`
+	AutoDetect                     bool                     `json:"auto_detect"`
+}
+
+type CNBBuildpack struct {
+	Name       string `json:"name"`
+	Key        string `json:"key,omitempty"`
+	Url        string `json:"url"`
+	SkipDetect bool   `json:"skip_detect"`
+}
+
+type CNBRegistryCredentials struct {
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 const CUSTOM_BUILDPACK = "custom"
 
 type Buildpack struct {
@@ -69,7 +94,59 @@ type StagingResponseForCC struct {
 	Result *json.RawMessage `json:"result,omitempty"`
 }
 
+type BuildpackStagingResult struct {
+	LifecycleType     string                     `json:"lifecycle_type"`
+	LifecycleMetadata BuildpackLifecycleMetadata `json:"lifecycle_metadata"`
+	ProcessTypes      map[string]string          `json:"process_types"`
+	ExecutionMetadata string                     `json:"execution_metadata"`
+}
+
+type BuildpackLifecycleMetadata struct {
+	BuildpackKey      string              `json:"buildpack_key,omitempty"`
+	DetectedBuildpack string              `json:"detected_buildpack"`
+	Buildpacks        []BuildpackMetadata `json:"buildpacks,omitempty"`
+}
+
+type BuildpackMetadata struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type DockerStagingResult struct {
+	LifecycleType     string                  `json:"lifecycle_type"`
+	LifecycleMetadata DockerLifecycleMetadata `json:"lifecycle_metadata"`
+	ProcessTypes      map[string]string       `json:"process_types"`
+	ExecutionMetadata string                  `json:"execution_metadata"`
+}
+
+type DockerLifecycleMetadata struct {
+	DockerImage string `json:"docker_image"`
+}
+
+type CNBStagingResult struct {
+	LifecycleType     string               `json:"lifecycle_type"`
+	LifecycleMetadata CNBLifecycleMetadata `json:"lifecycle_metadata"`
+	ProcessTypes      map[string]string    `json:"process_types"`
+	ExecutionMetadata string               `json:"execution_metadata"`
+}
+
+type CNBLifecycleMetadata struct {
+	Buildpacks []CNBBuildpackMetadata `json:"buildpacks"`
+	Stack      string                 `json:"stack"`
+	RunImage   string                 `json:"run_image,omitempty"`
+}
+
+type CNBBuildpackMetadata struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// StagingTaskAnnotation is the annotation of staging tasks and of the tasks
+// built by TaskDefinitionBuilder.
 type StagingTaskAnnotation struct {
+	Version            int    `json:"version,omitempty"`
 	Lifecycle          string `json:"lifecycle"`
 	CompletionCallback string `json:"completion_callback"`
 }
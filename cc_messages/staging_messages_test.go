@@ -97,6 +97,88 @@ var _ = Describe("StagingMessages", func() {
 		})
 	})
 
+	Describe("CNBStagingData", func() {
+		lifecycleDataJSON := `{
+			"app_bits_download_uri": "http://fake-download_uri",
+			"build_artifacts_cache_download_uri": "http://cache-download",
+			"build_artifacts_cache_upload_uri": "http://cache-upload",
+			"buildpacks": [
+				{"name": "paketo-buildpacks/node-engine", "url": "docker://gcr.io/paketo-buildpacks/node-engine:1.2.3"},
+				{"name": "custom", "key": "custom-guid", "url": "http://example.com/buildpack.cnb", "skip_detect": true}
+			],
+			"credentials": [
+				{"registry": "gcr.io", "username": "user", "password": "secret"}
+			],
+			"droplet_upload_uri": "http://droplet-upload-uri",
+			"stack": "cflinuxfs4",
+			"run_image": "paketobuildpacks/run:full-cnb",
+			"auto_detect": false
+		}`
+
+		It("unmarshals correctly", func() {
+			var lifecycleData cc_messages.CNBStagingData
+			err := json.Unmarshal([]byte(lifecycleDataJSON), &lifecycleData)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lifecycleData).To(Equal(cc_messages.CNBStagingData{
+				AppBitsDownloadUri:             "http://fake-download_uri",
+				BuildArtifactsCacheDownloadUri: "http://cache-download",
+				BuildArtifactsCacheUploadUri:   "http://cache-upload",
+				Buildpacks: []cc_messages.CNBBuildpack{
+					{Name: "paketo-buildpacks/node-engine", Url: "docker://gcr.io/paketo-buildpacks/node-engine:1.2.3"},
+					{Name: "custom", Key: "custom-guid", Url: "http://example.com/buildpack.cnb", SkipDetect: true},
+				},
+				Credentials: []cc_messages.CNBRegistryCredentials{
+					{Registry: "gcr.io", Username: "user", Password: "secret"},
+				},
+				DropletUploadUri: "http://droplet-upload-uri",
+				Stack:            "cflinuxfs4",
+				RunImage:         "paketobuildpacks/run:full-cnb",
+			}))
+			Expect(lifecycleData.Validate()).To(Succeed())
+		})
+
+		It("requires buildpacks unless auto detecting", func() {
+			lifecycleData := cc_messages.CNBStagingData{
+				AppBitsDownloadUri: "http://fake-download_uri",
+				DropletUploadUri:   "http://droplet-upload-uri",
+				Stack:              "cflinuxfs4",
+			}
+			Expect(lifecycleData.Validate()).To(MatchError(ContainSubstring("buildpacks")))
+
+			lifecycleData.AutoDetect = true
+			Expect(lifecycleData.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("CNBStagingResult", func() {
+		It("marshals to the CC's staging result JSON", func() {
+			result := cc_messages.CNBStagingResult{
+				LifecycleType: "cnb",
+				LifecycleMetadata: cc_messages.CNBLifecycleMetadata{
+					Buildpacks: []cc_messages.CNBBuildpackMetadata{
+						{Key: "paketo-buildpacks/node-engine", Name: "Node Engine", Version: "1.2.3"},
+					},
+					Stack:    "cflinuxfs4",
+					RunImage: "paketobuildpacks/run:full-cnb",
+				},
+				ProcessTypes:      map[string]string{"web": "node server.js"},
+				ExecutionMetadata: "",
+			}
+
+			Expect(json.Marshal(result)).To(MatchJSON(`{
+				"lifecycle_type": "cnb",
+				"lifecycle_metadata": {
+					"buildpacks": [{"key": "paketo-buildpacks/node-engine", "name": "Node Engine", "version": "1.2.3"}],
+					"stack": "cflinuxfs4",
+					"run_image": "paketobuildpacks/run:full-cnb"
+				},
+				"process_types": {"web": "node server.js"},
+				"execution_metadata": ""
+			}`))
+		})
+	})
+
 	Describe("Buildpack", func() {
 		Context("when skipping the detect phase is not specified", func() {
 			ccJSONFragment := `{
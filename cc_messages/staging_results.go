@@ -0,0 +1,136 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrStagingResultMissing = errors.New("staging response has no result")
+	ErrStagingResultInvalid = errors.New("staging result is not valid JSON")
+)
+
+type ErrStagingResultLifecycleMismatch struct {
+	Expected string
+	Actual   string
+}
+
+func (e ErrStagingResultLifecycleMismatch) Error() string {
+	return fmt.Sprintf("staging result is for lifecycle %q, not %q", e.Actual, e.Expected)
+}
+
+func (r BuildpackStagingResult) StartCommand() string {
+	return r.ProcessTypes["web"]
+}
+
+func (r DockerStagingResult) StartCommand() string {
+	return r.ProcessTypes["web"]
+}
+
+// ImageDigest returns the digest the staged image reference is pinned to, or
+// "" if it is referenced by tag only.
+func (r DockerStagingResult) ImageDigest() string {
+	_, digest, found := strings.Cut(r.LifecycleMetadata.DockerImage, "@")
+	if !found {
+		return ""
+	}
+	return digest
+}
+
+func NewBuildpackStagingResponse(result BuildpackStagingResult) (StagingResponseForCC, error) {
+	if result.LifecycleType == "" {
+		result.LifecycleType = BuildpackLifecycleName
+	}
+	return newStagingResultResponse(result)
+}
+
+func NewDockerStagingResponse(result DockerStagingResult) (StagingResponseForCC, error) {
+	if result.LifecycleType == "" {
+		result.LifecycleType = DockerLifecycleName
+	}
+	return newStagingResultResponse(result)
+}
+
+func NewCNBStagingResponse(result CNBStagingResult) (StagingResponseForCC, error) {
+	if result.LifecycleType == "" {
+		result.LifecycleType = CNBLifecycleName
+	}
+	return newStagingResultResponse(result)
+}
+
+func newStagingResultResponse(result interface{}) (StagingResponseForCC, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return StagingResponseForCC{}, err
+	}
+
+	raw := json.RawMessage(encoded)
+	return StagingResponseForCC{Result: &raw}, nil
+}
+
+func (r StagingResponseForCC) LifecycleType() (string, error) {
+	if r.Result == nil {
+		return "", ErrStagingResultMissing
+	}
+
+	var result struct {
+		LifecycleType string `json:"lifecycle_type"`
+	}
+	err := json.Unmarshal(*r.Result, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.LifecycleType, nil
+}
+
+func (r StagingResponseForCC) BuildpackResult() (BuildpackStagingResult, error) {
+	result := BuildpackStagingResult{}
+	err := r.decodeResult(BuildpackLifecycleName, &result)
+	return result, err
+}
+
+func (r StagingResponseForCC) DockerResult() (DockerStagingResult, error) {
+	result := DockerStagingResult{}
+	err := r.decodeResult(DockerLifecycleName, &result)
+	return result, err
+}
+
+func (r StagingResponseForCC) CNBResult() (CNBStagingResult, error) {
+	result := CNBStagingResult{}
+	err := r.decodeResult(CNBLifecycleName, &result)
+	return result, err
+}
+
+// DecodeResult returns a BuildpackStagingResult, DockerStagingResult or
+// CNBStagingResult depending on the lifecycle type of the result, or the raw
+// result for any other lifecycle.
+func (r StagingResponseForCC) DecodeResult() (interface{}, error) {
+	lifecycleType, err := r.LifecycleType()
+	if err != nil {
+		return nil, err
+	}
+
+	switch lifecycleType {
+	case BuildpackLifecycleName:
+		return r.BuildpackResult()
+	case DockerLifecycleName:
+		return r.DockerResult()
+	case CNBLifecycleName:
+		return r.CNBResult()
+	default:
+		return r.Result, nil
+	}
+}
+
+func (r StagingResponseForCC) decodeResult(lifecycle string, result interface{}) error {
+	lifecycleType, err := r.LifecycleType()
+	if err != nil {
+		return err
+	}
+	if lifecycleType != lifecycle {
+		return ErrStagingResultLifecycleMismatch{Expected: lifecycle, Actual: lifecycleType}
+	}
+	return json.Unmarshal(*r.Result, result)
+}
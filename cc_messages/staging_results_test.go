@@ -0,0 +1,122 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Staging results", func() {
+	rawResponse := func(result string) cc_messages.StagingResponseForCC {
+		raw := json.RawMessage(result)
+		return cc_messages.StagingResponseForCC{Result: &raw}
+	}
+
+	Describe("buildpack", func() {
+		const lifecycleResult = `{
+			"lifecycle_type": "buildpack",
+			"lifecycle_metadata": {
+				"buildpack_key": "ruby-key",
+				"detected_buildpack": "ruby 1.8.10",
+				"buildpacks": [{"key": "ruby-key", "name": "ruby_buildpack", "version": "1.8.10"}]
+			},
+			"process_types": {"web": "bundle exec rackup", "worker": "bundle exec sidekiq"},
+			"execution_metadata": ""
+		}`
+
+		It("decodes the lifecycle result", func() {
+			result, err := rawResponse(lifecycleResult).BuildpackResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(cc_messages.BuildpackStagingResult{
+				LifecycleType: "buildpack",
+				LifecycleMetadata: cc_messages.BuildpackLifecycleMetadata{
+					BuildpackKey:      "ruby-key",
+					DetectedBuildpack: "ruby 1.8.10",
+					Buildpacks:        []cc_messages.BuildpackMetadata{{Key: "ruby-key", Name: "ruby_buildpack", Version: "1.8.10"}},
+				},
+				ProcessTypes: map[string]string{"web": "bundle exec rackup", "worker": "bundle exec sidekiq"},
+			}))
+			Expect(result.StartCommand()).To(Equal("bundle exec rackup"))
+		})
+
+		It("round-trips through a StagingResponseForCC", func() {
+			response, err := cc_messages.NewBuildpackStagingResponse(cc_messages.BuildpackStagingResult{
+				LifecycleMetadata: cc_messages.BuildpackLifecycleMetadata{DetectedBuildpack: "go"},
+				ProcessTypes:      map[string]string{"web": "./app"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error).To(BeNil())
+
+			result, err := response.BuildpackResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.LifecycleType).To(Equal("buildpack"))
+			Expect(result.LifecycleMetadata.DetectedBuildpack).To(Equal("go"))
+		})
+	})
+
+	Describe("docker", func() {
+		It("round-trips through a StagingResponseForCC", func() {
+			response, err := cc_messages.NewDockerStagingResponse(cc_messages.DockerStagingResult{
+				LifecycleMetadata: cc_messages.DockerLifecycleMetadata{DockerImage: "cloudfoundry/diego-docker-app@sha256:abc123"},
+				ProcessTypes:      map[string]string{"web": "/myapp"},
+				ExecutionMetadata: `{"cmd":["/myapp"]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*response.Result).To(MatchJSON(`{
+				"lifecycle_type": "docker",
+				"lifecycle_metadata": {"docker_image": "cloudfoundry/diego-docker-app@sha256:abc123"},
+				"process_types": {"web": "/myapp"},
+				"execution_metadata": "{\"cmd\":[\"/myapp\"]}"
+			}`))
+
+			result, err := response.DockerResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.ImageDigest()).To(Equal("sha256:abc123"))
+			Expect(result.StartCommand()).To(Equal("/myapp"))
+		})
+
+		It("has no digest for tagged images", func() {
+			result := cc_messages.DockerStagingResult{LifecycleMetadata: cc_messages.DockerLifecycleMetadata{DockerImage: "cloudfoundry/diego-docker-app:latest"}}
+			Expect(result.ImageDigest()).To(BeEmpty())
+		})
+	})
+
+	Describe("DecodeResult", func() {
+		It("returns the typed result for known lifecycles", func() {
+			result, err := rawResponse(`{"lifecycle_type":"docker","lifecycle_metadata":{"docker_image":"busybox"}}`).DecodeResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeAssignableToTypeOf(cc_messages.DockerStagingResult{}))
+
+			result, err = rawResponse(`{"lifecycle_type":"cnb","lifecycle_metadata":{"stack":"cflinuxfs4"}}`).DecodeResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.(cc_messages.CNBStagingResult).LifecycleMetadata.Stack).To(Equal("cflinuxfs4"))
+		})
+
+		It("keeps the raw result for unknown lifecycles", func() {
+			response := rawResponse(`{"lifecycle_type":"kpack","image":"registry/app"}`)
+			result, err := response.DecodeResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(response.Result))
+		})
+	})
+
+	Describe("errors", func() {
+		It("fails without a result", func() {
+			_, err := cc_messages.StagingResponseForCC{Error: &cc_messages.StagingError{Id: cc_messages.STAGING_ERROR}}.BuildpackResult()
+			Expect(err).To(Equal(cc_messages.ErrStagingResultMissing))
+		})
+
+		It("fails when the lifecycle does not match", func() {
+			_, err := rawResponse(`{"lifecycle_type":"docker"}`).BuildpackResult()
+			Expect(err).To(Equal(cc_messages.ErrStagingResultLifecycleMismatch{Expected: "buildpack", Actual: "docker"}))
+		})
+
+		It("fails on malformed results", func() {
+			_, err := rawResponse(`{`).DecodeResult()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
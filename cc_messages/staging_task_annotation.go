@@ -0,0 +1,70 @@
+package cc_messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// StagingTaskAnnotationVersion is written into every encoded annotation.
+// Annotations written before versioning was introduced decode as version 0
+// and carry the same fields as version 1. Fields added in later versions are
+// ignored by older readers, so in-flight tasks survive a rolling deploy in
+// either direction.
+const StagingTaskAnnotationVersion = 1
+
+var ErrStagingTaskAnnotationMissing = errors.New("task has no staging annotation")
+
+type ErrStagingTaskAnnotationInvalid struct {
+	Err error
+}
+
+func (e ErrStagingTaskAnnotationInvalid) Error() string {
+	return fmt.Sprintf("invalid staging task annotation: %s", e.Err)
+}
+
+func (e ErrStagingTaskAnnotationInvalid) Unwrap() error {
+	return e.Err
+}
+
+func (a StagingTaskAnnotation) Encode() (string, error) {
+	a.Version = StagingTaskAnnotationVersion
+
+	encoded, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func (a StagingTaskAnnotation) AttachTo(taskDefinition *models.TaskDefinition) error {
+	annotation, err := a.Encode()
+	if err != nil {
+		return err
+	}
+
+	taskDefinition.Annotation = annotation
+	return nil
+}
+
+func DecodeStagingTaskAnnotation(annotation string) (StagingTaskAnnotation, error) {
+	if annotation == "" {
+		return StagingTaskAnnotation{}, ErrStagingTaskAnnotationMissing
+	}
+
+	decoded := StagingTaskAnnotation{}
+	err := json.Unmarshal([]byte(annotation), &decoded)
+	if err != nil {
+		return StagingTaskAnnotation{}, ErrStagingTaskAnnotationInvalid{Err: err}
+	}
+	return decoded, nil
+}
+
+func StagingTaskAnnotationFromTask(task *models.Task) (StagingTaskAnnotation, error) {
+	if task.TaskDefinition == nil {
+		return StagingTaskAnnotation{}, ErrStagingTaskAnnotationMissing
+	}
+	return DecodeStagingTaskAnnotation(task.TaskDefinition.Annotation)
+}
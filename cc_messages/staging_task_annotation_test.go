@@ -0,0 +1,73 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StagingTaskAnnotation", func() {
+	var annotation cc_messages.StagingTaskAnnotation
+
+	BeforeEach(func() {
+		annotation = cc_messages.StagingTaskAnnotation{
+			Lifecycle:          "buildpack",
+			CompletionCallback: "https://cc.service.cf.internal/internal/v3/staging/guid/build_completed",
+		}
+	})
+
+	It("encodes the current version", func() {
+		encoded, err := annotation.Encode()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encoded).To(MatchJSON(`{
+			"version": 1,
+			"lifecycle": "buildpack",
+			"completion_callback": "https://cc.service.cf.internal/internal/v3/staging/guid/build_completed"
+		}`))
+	})
+
+	It("round-trips through a BBS task", func() {
+		taskDefinition := &models.TaskDefinition{}
+		Expect(annotation.AttachTo(taskDefinition)).To(Succeed())
+
+		decoded, err := cc_messages.StagingTaskAnnotationFromTask(&models.Task{TaskDefinition: taskDefinition})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Version).To(Equal(cc_messages.StagingTaskAnnotationVersion))
+		Expect(decoded.Lifecycle).To(Equal("buildpack"))
+		Expect(decoded.CompletionCallback).To(Equal(annotation.CompletionCallback))
+	})
+
+	It("decodes annotations written before versioning", func() {
+		decoded, err := cc_messages.DecodeStagingTaskAnnotation(`{"lifecycle":"docker","completion_callback":"https://cc/callback"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(cc_messages.StagingTaskAnnotation{Lifecycle: "docker", CompletionCallback: "https://cc/callback"}))
+	})
+
+	It("decodes annotations from newer versions", func() {
+		decoded, err := cc_messages.DecodeStagingTaskAnnotation(`{"version":2,"lifecycle":"cnb","completion_callback":"https://cc/callback","staging_guid":"guid","start_time":1257894000}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(cc_messages.StagingTaskAnnotation{Version: 2, Lifecycle: "cnb", CompletionCallback: "https://cc/callback"}))
+	})
+
+	It("fails on missing annotations", func() {
+		_, err := cc_messages.DecodeStagingTaskAnnotation("")
+		Expect(err).To(Equal(cc_messages.ErrStagingTaskAnnotationMissing))
+
+		_, err = cc_messages.StagingTaskAnnotationFromTask(&models.Task{})
+		Expect(err).To(Equal(cc_messages.ErrStagingTaskAnnotationMissing))
+	})
+
+	It("fails on malformed annotations", func() {
+		_, err := cc_messages.DecodeStagingTaskAnnotation("not-json")
+
+		var invalid cc_messages.ErrStagingTaskAnnotationInvalid
+		Expect(errors.As(err, &invalid)).To(BeTrue())
+		var syntaxErr *json.SyntaxError
+		Expect(errors.As(err, &syntaxErr)).To(BeTrue())
+	})
+})
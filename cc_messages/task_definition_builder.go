@@ -0,0 +1,130 @@
+package cc_messages
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+)
+
+const TaskLogSource = "APP/TASK"
+
+type TaskDefinitionBuilder struct {
+	lifecycles    flags.LifecycleMap
+	fileServerURL string
+}
+
+func NewTaskDefinitionBuilder(lifecycles flags.LifecycleMap, fileServerURL string) *TaskDefinitionBuilder {
+	return &TaskDefinitionBuilder{
+		lifecycles:    lifecycles,
+		fileServerURL: fileServerURL,
+	}
+}
+
+func (b *TaskDefinitionBuilder) Build(taskRequest *TaskRequestFromCC) (*models.TaskDefinition, error) {
+	err := taskRequest.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	lifecycle := LifecycleForTask(taskRequest)
+	lifecycleURL, err := lifecycleDownloadURL(b.lifecycles, lifecycle, b.fileServerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeMounts, err := convertVolumeMounts(taskRequest.VolumeMounts)
+	if err != nil {
+		return nil, err
+	}
+
+	user := "vcap"
+	rootFS := models.PreloadedRootFS(taskRequest.RootFs)
+	var actions []models.ActionInterface
+
+	if taskRequest.Lifecycle == DockerLifecycleName {
+		user = "root"
+		rootFS, err = DockerRootFS(taskRequest.DockerPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		checksumAlgorithm, checksumValue := dropletChecksum(taskRequest.DropletHash)
+		actions = append(actions, &models.DownloadAction{
+			From:              taskRequest.DropletUri,
+			To:                ".",
+			User:              user,
+			ChecksumAlgorithm: checksumAlgorithm,
+			ChecksumValue:     checksumValue,
+		})
+	}
+
+	logSource := taskRequest.LogSource
+	if logSource == "" {
+		logSource = TaskLogSource
+	}
+
+	actions = append(actions, &models.RunAction{
+		User:           user,
+		Path:           lifecycleDir + "/launcher",
+		Args:           []string{"app", taskRequest.Command, ""},
+		Env:            taskRequest.EnvironmentVariables,
+		LogSource:      logSource,
+		ResourceLimits: &models.ResourceLimits{OptionalNofile: &models.ResourceLimits_Nofile{Nofile: DefaultFileDescriptorLimit}},
+	})
+
+	taskDefinition := &models.TaskDefinition{
+		RootFs:                rootFS,
+		EnvironmentVariables:  []*models.EnvironmentVariable{{Name: "LANG", Value: DefaultLANG}},
+		Action:                models.WrapAction(models.Serial(actions...)),
+		DiskMb:                int32(taskRequest.DiskMb),
+		MemoryMb:              int32(taskRequest.MemoryMb),
+		CpuWeight:             cpuWeight(taskRequest.MemoryMb),
+		LogSource:             logSource,
+		LogGuid:               taskRequest.LogGuid,
+		CompletionCallbackUrl: taskRequest.CompletionCallbackUrl,
+		EgressRules:           taskRequest.EgressRules,
+		CachedDependencies: []*models.CachedDependency{{
+			From:     lifecycleURL,
+			To:       lifecycleDir,
+			CacheKey: lifecycleCacheKey(lifecycle),
+		}},
+		TrustedSystemCertificatesPath: TrustedSystemCertificatesPath,
+		VolumeMounts:                  volumeMounts,
+		PlacementTags:                 placementTags(taskRequest.IsolationSegment),
+		ImageUsername:                 taskRequest.DockerUser,
+		ImagePassword:                 taskRequest.DockerPassword,
+	}
+
+	err = StagingTaskAnnotation{
+		Lifecycle:          taskRequest.Lifecycle,
+		CompletionCallback: taskRequest.CompletionCallbackUrl,
+	}.AttachTo(taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	return taskDefinition, nil
+}
+
+func (b *TaskDefinitionBuilder) BuildDesireTaskRequest(taskRequest *TaskRequestFromCC) (*models.DesireTaskRequest, error) {
+	taskDefinition, err := b.Build(taskRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DesireTaskRequest{
+		TaskDefinition: taskDefinition,
+		TaskGuid:       taskRequest.TaskGuid,
+		Domain:         RunningTaskDomain,
+	}, nil
+}
+
+func LifecycleForTask(taskRequest *TaskRequestFromCC) string {
+	switch taskRequest.Lifecycle {
+	case DockerLifecycleName:
+		return DockerLifecycleName
+	case CNBLifecycleName:
+		return CNBLifecycleName + "/" + taskRequest.RootFs
+	default:
+		return BuildpackLifecycleName + "/" + taskRequest.RootFs
+	}
+}
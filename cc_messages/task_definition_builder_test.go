@@ -0,0 +1,175 @@
+package cc_messages_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+	"code.cloudfoundry.org/runtimeschema/cc_messages/flags"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TaskDefinitionBuilder", func() {
+	var (
+		builder        *cc_messages.TaskDefinitionBuilder
+		taskRequest    *cc_messages.TaskRequestFromCC
+		taskDefinition *models.TaskDefinition
+		buildErr       error
+	)
+
+	BeforeEach(func() {
+		lifecycles := flags.LifecycleMap{
+			"buildpack/cflinuxfs4": "buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+			"docker":               "docker_app_lifecycle/docker_app_lifecycle.tgz",
+			"cnb/cflinuxfs4":       "cnb_app_lifecycle/cnb_app_lifecycle.tgz",
+		}
+		builder = cc_messages.NewTaskDefinitionBuilder(lifecycles, "http://file-server.com")
+
+		taskRequest = &cc_messages.TaskRequestFromCC{
+			TaskGuid:              "task-guid",
+			LogGuid:               "log-guid",
+			MemoryMb:              256,
+			DiskMb:                1024,
+			Lifecycle:             "buildpack",
+			EnvironmentVariables:  []*models.EnvironmentVariable{{Name: "FOO", Value: "BAR"}},
+			DropletUri:            "http://the-droplet.uri.com",
+			DropletHash:           "3a2b5c8f0e1d4a6b7c9d2e3f4a5b6c7d8e9f0a1b",
+			RootFs:                "cflinuxfs4",
+			CompletionCallbackUrl: "http://api.cc.com/internal/v3/tasks/task-guid/completed",
+			Command:               "rake db:migrate",
+			IsolationSegment:      "segment-1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		taskDefinition, buildErr = builder.Build(taskRequest)
+	})
+
+	Context("with a buildpack task", func() {
+		It("builds a valid TaskDefinition", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(taskDefinition.Validate()).To(Succeed())
+
+			Expect(taskDefinition.RootFs).To(Equal("preloaded:cflinuxfs4"))
+			Expect(taskDefinition.MemoryMb).To(BeEquivalentTo(256))
+			Expect(taskDefinition.DiskMb).To(BeEquivalentTo(1024))
+			Expect(taskDefinition.LogGuid).To(Equal("log-guid"))
+			Expect(taskDefinition.LogSource).To(Equal(cc_messages.TaskLogSource))
+			Expect(taskDefinition.CompletionCallbackUrl).To(Equal("http://api.cc.com/internal/v3/tasks/task-guid/completed"))
+			Expect(taskDefinition.PlacementTags).To(Equal([]string{"segment-1"}))
+			Expect(taskDefinition.Annotation).To(MatchJSON(`{
+				"version": 1,
+				"lifecycle": "buildpack",
+				"completion_callback": "http://api.cc.com/internal/v3/tasks/task-guid/completed"
+			}`))
+		})
+
+		It("sets the task environment on the run action only", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(taskDefinition.EnvironmentVariables).To(Equal([]*models.EnvironmentVariable{{Name: "LANG", Value: cc_messages.DefaultLANG}}))
+		})
+
+		It("caches the lifecycle", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(taskDefinition.CachedDependencies).To(Equal([]*models.CachedDependency{{
+				From:     "http://file-server.com/v1/static/buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+				To:       "/tmp/lifecycle",
+				CacheKey: "buildpack-cflinuxfs4-lifecycle",
+			}}))
+		})
+
+		It("downloads the droplet and runs the command", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(taskDefinition.Action).To(Equal(models.WrapAction(models.Serial(
+				&models.DownloadAction{
+					From:              "http://the-droplet.uri.com",
+					To:                ".",
+					User:              "vcap",
+					ChecksumAlgorithm: "sha1",
+					ChecksumValue:     "3a2b5c8f0e1d4a6b7c9d2e3f4a5b6c7d8e9f0a1b",
+				},
+				&models.RunAction{
+					User:           "vcap",
+					Path:           "/tmp/lifecycle/launcher",
+					Args:           []string{"app", "rake db:migrate", ""},
+					Env:            []*models.EnvironmentVariable{{Name: "FOO", Value: "BAR"}},
+					LogSource:      cc_messages.TaskLogSource,
+					ResourceLimits: &models.ResourceLimits{OptionalNofile: &models.ResourceLimits_Nofile{Nofile: cc_messages.DefaultFileDescriptorLimit}},
+				},
+			))))
+		})
+
+		Context("when there is no lifecycle for the rootfs", func() {
+			BeforeEach(func() {
+				taskRequest.RootFs = "windows"
+			})
+
+			It("errors", func() {
+				Expect(buildErr).To(Equal(cc_messages.ErrNoLifecycleDefined))
+			})
+		})
+
+		Context("when the request is invalid", func() {
+			BeforeEach(func() {
+				taskRequest.Command = ""
+			})
+
+			It("returns the validation error", func() {
+				Expect(buildErr).To(BeAssignableToTypeOf(models.ValidationError{}))
+			})
+		})
+	})
+
+	Context("with a cnb task", func() {
+		BeforeEach(func() {
+			taskRequest.Lifecycle = "cnb"
+		})
+
+		It("runs the droplet with the cnb lifecycle for the rootfs", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(taskDefinition.Validate()).To(Succeed())
+
+			Expect(taskDefinition.RootFs).To(Equal("preloaded:cflinuxfs4"))
+			Expect(taskDefinition.CachedDependencies[0].From).To(Equal("http://file-server.com/v1/static/cnb_app_lifecycle/cnb_app_lifecycle.tgz"))
+			Expect(taskDefinition.CachedDependencies[0].CacheKey).To(Equal("cnb-cflinuxfs4-lifecycle"))
+			Expect(taskDefinition.Action.SerialAction.Actions[0].DownloadAction.From).To(Equal(taskRequest.DropletUri))
+		})
+	})
+
+	Context("with a docker task", func() {
+		BeforeEach(func() {
+			taskRequest.Lifecycle = "docker"
+			taskRequest.DropletUri = ""
+			taskRequest.DropletHash = ""
+			taskRequest.RootFs = ""
+			taskRequest.DockerPath = "cloudfoundry/diego-docker-app:latest"
+			taskRequest.DockerUser = "user"
+			taskRequest.DockerPassword = "password"
+		})
+
+		It("builds a valid TaskDefinition from the image", func() {
+			Expect(buildErr).NotTo(HaveOccurred())
+			Expect(taskDefinition.Validate()).To(Succeed())
+
+			Expect(taskDefinition.RootFs).To(Equal("docker:///cloudfoundry/diego-docker-app#latest"))
+			Expect(taskDefinition.ImageUsername).To(Equal("user"))
+			Expect(taskDefinition.ImagePassword).To(Equal("password"))
+			Expect(taskDefinition.CachedDependencies[0].CacheKey).To(Equal("docker-lifecycle"))
+
+			actions := taskDefinition.Action.SerialAction.Actions
+			Expect(actions).To(HaveLen(1))
+			Expect(actions[0].RunAction.User).To(Equal("root"))
+		})
+	})
+
+	Describe("BuildDesireTaskRequest", func() {
+		It("desires the task in the running task domain", func() {
+			request, err := builder.BuildDesireTaskRequest(taskRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(request.Validate()).To(Succeed())
+			Expect(request.TaskGuid).To(Equal("task-guid"))
+			Expect(request.Domain).To(Equal(cc_messages.RunningTaskDomain))
+			Expect(request.TaskDefinition).To(Equal(taskDefinition))
+		})
+	})
+})
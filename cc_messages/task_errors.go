@@ -0,0 +1,95 @@
+package cc_messages
+
+import (
+	"errors"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+const (
+	TASK_ERROR                    TaskErrorID = "TaskError"
+	TASK_INVALID_REQUEST          TaskErrorID = "InvalidRequest"
+	TASK_ALREADY_EXISTS           TaskErrorID = "TaskAlreadyExists"
+	TASK_NOT_FOUND                TaskErrorID = "TaskNotFound"
+	TASK_INSUFFICIENT_RESOURCES   TaskErrorID = "InsufficientResources"
+	TASK_NO_COMPATIBLE_CELL       TaskErrorID = "NoCompatibleCell"
+	TASK_CELL_COMMUNICATION_ERROR TaskErrorID = "CellCommunicationError"
+	TASK_BBS_COMMUNICATION_ERROR  TaskErrorID = "BBSCommunicationError"
+	TASK_FAILED                   TaskErrorID = "TaskFailed"
+)
+
+// TaskErrorIDForBBSError classifies an error returned by the BBS when
+// desiring or fetching a task. Errors that are not BBS errors come from
+// failing to reach the BBS at all.
+func TaskErrorIDForBBSError(err error) TaskErrorID {
+	var bbsErr *models.Error
+	if !errors.As(err, &bbsErr) {
+		return TASK_BBS_COMMUNICATION_ERROR
+	}
+
+	switch bbsErr.Type {
+	case models.Error_InvalidRequest, models.Error_InvalidRecord, models.Error_InvalidJSON, models.Error_InvalidProtobufMessage:
+		return TASK_INVALID_REQUEST
+	case models.Error_ResourceExists:
+		return TASK_ALREADY_EXISTS
+	case models.Error_ResourceNotFound:
+		return TASK_NOT_FOUND
+	case models.Error_InvalidResponse, models.Error_FailedToOpenEnvelope, models.Error_Deserialize,
+		models.Error_Deadlock, models.Error_Timeout, models.Error_LockCollision, models.Error_Unrecoverable:
+		return TASK_BBS_COMMUNICATION_ERROR
+	default:
+		return TASK_ERROR
+	}
+}
+
+// TaskErrorForBBSError returns the zero TaskError when there is no error.
+func TaskErrorForBBSError(err error) TaskError {
+	if err == nil {
+		return TaskError{}
+	}
+
+	return TaskError{
+		Id:      TaskErrorIDForBBSError(err),
+		Message: err.Error(),
+	}
+}
+
+// TaskErrorIDForFailureReason classifies the failure reason of a BBS task,
+// recognising the placement failures reported by the auctioneer.
+func TaskErrorIDForFailureReason(failureReason string) TaskErrorID {
+	reason := strings.ToLower(failureReason)
+	switch {
+	case strings.Contains(reason, "insufficient resources"):
+		return TASK_INSUFFICIENT_RESOURCES
+	case strings.Contains(reason, "found no compatible cell"):
+		return TASK_NO_COMPATIBLE_CELL
+	case strings.Contains(reason, "unable to communicate to compatible cells"):
+		return TASK_CELL_COMMUNICATION_ERROR
+	default:
+		return TASK_FAILED
+	}
+}
+
+func TaskErrorForTask(task *models.Task) TaskError {
+	return TaskError{
+		Id:      TaskErrorIDForFailureReason(task.FailureReason),
+		Message: task.FailureReason,
+	}
+}
+
+func NewTaskFailResponseForCC(taskGuid, failureReason string) TaskFailResponseForCC {
+	return TaskFailResponseForCC{
+		TaskGuid:      taskGuid,
+		Failed:        true,
+		FailureReason: failureReason,
+	}
+}
+
+func TaskFailResponseForTask(task *models.Task) TaskFailResponseForCC {
+	return TaskFailResponseForCC{
+		TaskGuid:      task.TaskGuid,
+		Failed:        task.Failed,
+		FailureReason: task.FailureReason,
+	}
+}
@@ -0,0 +1,86 @@
+package cc_messages_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Task errors", func() {
+	DescribeTable("TaskErrorIDForBBSError",
+		func(err error, expected cc_messages.TaskErrorID) {
+			Expect(cc_messages.TaskErrorIDForBBSError(err)).To(Equal(expected))
+		},
+		Entry("invalid request", models.NewError(models.Error_InvalidRequest, "bad"), cc_messages.TASK_INVALID_REQUEST),
+		Entry("invalid record", models.ErrBadRequest, cc_messages.TASK_INVALID_REQUEST),
+		Entry("existing task", models.ErrResourceExists, cc_messages.TASK_ALREADY_EXISTS),
+		Entry("missing task", models.ErrResourceNotFound, cc_messages.TASK_NOT_FOUND),
+		Entry("deadlock", models.ErrDeadlock, cc_messages.TASK_BBS_COMMUNICATION_ERROR),
+		Entry("timeout", models.NewError(models.Error_Timeout, "slow"), cc_messages.TASK_BBS_COMMUNICATION_ERROR),
+		Entry("unreachable bbs", errors.New("dial tcp: connection refused"), cc_messages.TASK_BBS_COMMUNICATION_ERROR),
+		Entry("unknown", models.ErrUnknownError, cc_messages.TASK_ERROR),
+	)
+
+	It("builds a TaskError from a BBS error", func() {
+		Expect(cc_messages.TaskErrorForBBSError(models.ErrResourceExists)).To(Equal(cc_messages.TaskError{
+			Id:      cc_messages.TASK_ALREADY_EXISTS,
+			Message: models.ErrResourceExists.Error(),
+		}))
+	})
+
+	It("builds the zero TaskError when there is no error", func() {
+		Expect(cc_messages.TaskErrorForBBSError(nil)).To(Equal(cc_messages.TaskError{}))
+	})
+
+	DescribeTable("TaskErrorIDForFailureReason",
+		func(reason string, expected cc_messages.TaskErrorID) {
+			Expect(cc_messages.TaskErrorIDForFailureReason(reason)).To(Equal(expected))
+		},
+		Entry("insufficient memory", "insufficient resources: memory", cc_messages.TASK_INSUFFICIENT_RESOURCES),
+		Entry("no cell with the stack", "found no compatible cell", cc_messages.TASK_NO_COMPATIBLE_CELL),
+		Entry("no cell with the placement tags", "found no compatible cell with placement tags \"gpu\"", cc_messages.TASK_NO_COMPATIBLE_CELL),
+		Entry("cells unreachable", "unable to communicate to compatible cells", cc_messages.TASK_CELL_COMMUNICATION_ERROR),
+		Entry("exited", "APP/TASK/migrate: Exited with status 1", cc_messages.TASK_FAILED),
+		Entry("empty", "", cc_messages.TASK_FAILED),
+	)
+
+	Describe("from a failed task", func() {
+		var task *models.Task
+
+		BeforeEach(func() {
+			task = &models.Task{
+				TaskGuid:      "task-guid",
+				State:         models.Task_Completed,
+				Failed:        true,
+				FailureReason: "insufficient resources: disk",
+			}
+		})
+
+		It("builds the TaskError", func() {
+			Expect(cc_messages.TaskErrorForTask(task)).To(Equal(cc_messages.TaskError{
+				Id:      cc_messages.TASK_INSUFFICIENT_RESOURCES,
+				Message: "insufficient resources: disk",
+			}))
+		})
+
+		It("builds the TaskFailResponseForCC", func() {
+			Expect(cc_messages.TaskFailResponseForTask(task)).To(Equal(cc_messages.TaskFailResponseForCC{
+				TaskGuid:      "task-guid",
+				Failed:        true,
+				FailureReason: "insufficient resources: disk",
+			}))
+		})
+	})
+
+	It("builds a failure for a task BBS does not know about", func() {
+		Expect(cc_messages.NewTaskFailResponseForCC("task-guid", "task was lost")).To(Equal(cc_messages.TaskFailResponseForCC{
+			TaskGuid:      "task-guid",
+			Failed:        true,
+			FailureReason: "task was lost",
+		}))
+	})
+})
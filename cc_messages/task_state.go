@@ -0,0 +1,170 @@
+package cc_messages
+
+import (
+	"fmt"
+	"sort"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+type TaskState string
+
+const (
+	TaskStatePending   TaskState = "PENDING"
+	TaskStateRunning   TaskState = "RUNNING"
+	TaskStateCanceling TaskState = "CANCELING"
+	TaskStateSucceeded TaskState = "SUCCEEDED"
+	TaskStateFailed    TaskState = "FAILED"
+)
+
+var taskStateTransitions = map[TaskState][]TaskState{
+	TaskStatePending:   {TaskStateRunning, TaskStateCanceling, TaskStateSucceeded, TaskStateFailed},
+	TaskStateRunning:   {TaskStateCanceling, TaskStateSucceeded, TaskStateFailed},
+	TaskStateCanceling: {TaskStateSucceeded, TaskStateFailed},
+	TaskStateSucceeded: {},
+	TaskStateFailed:    {},
+}
+
+type ErrInvalidTaskState struct {
+	State string
+}
+
+func (e ErrInvalidTaskState) Error() string {
+	return fmt.Sprintf("invalid task state: %q", e.State)
+}
+
+type ErrInvalidTaskStateTransition struct {
+	From TaskState
+	To   TaskState
+}
+
+func (e ErrInvalidTaskStateTransition) Error() string {
+	return fmt.Sprintf("invalid task state transition from %s to %s", e.From, e.To)
+}
+
+func (s TaskState) Valid() bool {
+	_, ok := taskStateTransitions[s]
+	return ok
+}
+
+func (s TaskState) Terminal() bool {
+	return s == TaskStateSucceeded || s == TaskStateFailed
+}
+
+// CanTransitionTo reports whether a task may move from s to next. Staying in
+// the same state is always allowed so that repeated updates are harmless.
+func (s TaskState) CanTransitionTo(next TaskState) bool {
+	if !s.Valid() || !next.Valid() {
+		return false
+	}
+	if s == next {
+		return true
+	}
+
+	for _, allowed := range taskStateTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s TaskState) Transition(next TaskState) (TaskState, error) {
+	if !next.Valid() {
+		return s, ErrInvalidTaskState{State: string(next)}
+	}
+	if !s.CanTransitionTo(next) {
+		return s, ErrInvalidTaskStateTransition{From: s, To: next}
+	}
+	return next, nil
+}
+
+// TaskStateFromBBS converts the state of a BBS task; completed and resolving
+// tasks map to SUCCEEDED or FAILED depending on failed.
+func TaskStateFromBBS(state models.Task_State, failed bool) (TaskState, error) {
+	switch state {
+	case models.Task_Pending:
+		return TaskStatePending, nil
+	case models.Task_Running:
+		return TaskStateRunning, nil
+	case models.Task_Completed, models.Task_Resolving:
+		if failed {
+			return TaskStateFailed, nil
+		}
+		return TaskStateSucceeded, nil
+	default:
+		return "", ErrInvalidTaskState{State: state.String()}
+	}
+}
+
+func TaskStateForTask(task *models.Task) (TaskState, error) {
+	return TaskStateFromBBS(task.State, task.Failed)
+}
+
+type TaskReconciliation struct {
+	// Cancel lists BBS tasks that CC is canceling or no longer knows about.
+	Cancel []string
+	// FailToCC lists tasks CC considers running or canceling but BBS no
+	// longer has; CC should be told they failed. A canceling task missing
+	// from BBS has already been cancelled and deleted. Pending tasks missing
+	// from BBS are left alone, as CC records them before desiring them.
+	FailToCC []CCTaskState
+	// ResendCallback lists completed BBS tasks whose completion CC has not
+	// recorded.
+	ResendCallback []*models.Task
+}
+
+// ReconcileTasks compares the complete list of task states known to CC, as
+// collected from all CCTaskStatesResponse pages, with the BBS tasks of the
+// same domain.
+func ReconcileTasks(ccTaskStates []CCTaskState, bbsTasks []*models.Task) TaskReconciliation {
+	reconciliation := TaskReconciliation{
+		Cancel:         []string{},
+		FailToCC:       []CCTaskState{},
+		ResendCallback: []*models.Task{},
+	}
+
+	tasks := make(map[string]*models.Task, len(bbsTasks))
+	for _, task := range bbsTasks {
+		tasks[task.TaskGuid] = task
+	}
+
+	known := make(map[string]bool, len(ccTaskStates))
+	for _, ccTask := range ccTaskStates {
+		known[ccTask.TaskGuid] = true
+		if ccTask.State.Terminal() {
+			continue
+		}
+
+		task, ok := tasks[ccTask.TaskGuid]
+		switch {
+		case !ok:
+			if ccTask.State != TaskStatePending {
+				reconciliation.FailToCC = append(reconciliation.FailToCC, ccTask)
+			}
+		case task.State == models.Task_Completed:
+			reconciliation.ResendCallback = append(reconciliation.ResendCallback, task)
+		case ccTask.State == TaskStateCanceling && isActive(task):
+			reconciliation.Cancel = append(reconciliation.Cancel, task.TaskGuid)
+		}
+	}
+
+	for _, task := range bbsTasks {
+		if !known[task.TaskGuid] && isActive(task) {
+			reconciliation.Cancel = append(reconciliation.Cancel, task.TaskGuid)
+		}
+	}
+
+	sort.Strings(reconciliation.Cancel)
+	sort.Slice(reconciliation.FailToCC, func(i, j int) bool {
+		return reconciliation.FailToCC[i].TaskGuid < reconciliation.FailToCC[j].TaskGuid
+	})
+	sort.Slice(reconciliation.ResendCallback, func(i, j int) bool {
+		return reconciliation.ResendCallback[i].TaskGuid < reconciliation.ResendCallback[j].TaskGuid
+	})
+	return reconciliation
+}
+
+func isActive(task *models.Task) bool {
+	return task.State == models.Task_Pending || task.State == models.Task_Running
+}
@@ -0,0 +1,124 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TaskState", func() {
+	DescribeTable("transitions",
+		func(from, to cc_messages.TaskState, allowed bool) {
+			Expect(from.CanTransitionTo(to)).To(Equal(allowed))
+
+			next, err := from.Transition(to)
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(next).To(Equal(to))
+			} else {
+				Expect(err).To(Equal(cc_messages.ErrInvalidTaskStateTransition{From: from, To: to}))
+				Expect(next).To(Equal(from))
+			}
+		},
+		Entry("pending to running", cc_messages.TaskStatePending, cc_messages.TaskStateRunning, true),
+		Entry("pending to failed", cc_messages.TaskStatePending, cc_messages.TaskStateFailed, true),
+		Entry("running to canceling", cc_messages.TaskStateRunning, cc_messages.TaskStateCanceling, true),
+		Entry("running to succeeded", cc_messages.TaskStateRunning, cc_messages.TaskStateSucceeded, true),
+		Entry("canceling to failed", cc_messages.TaskStateCanceling, cc_messages.TaskStateFailed, true),
+		Entry("running to running", cc_messages.TaskStateRunning, cc_messages.TaskStateRunning, true),
+		Entry("running to pending", cc_messages.TaskStateRunning, cc_messages.TaskStatePending, false),
+		Entry("canceling to running", cc_messages.TaskStateCanceling, cc_messages.TaskStateRunning, false),
+		Entry("succeeded to running", cc_messages.TaskStateSucceeded, cc_messages.TaskStateRunning, false),
+		Entry("failed to succeeded", cc_messages.TaskStateFailed, cc_messages.TaskStateSucceeded, false),
+	)
+
+	It("rejects unknown states", func() {
+		_, err := cc_messages.TaskStatePending.Transition("BOGUS")
+		Expect(err).To(Equal(cc_messages.ErrInvalidTaskState{State: "BOGUS"}))
+		Expect(cc_messages.TaskState("BOGUS").CanTransitionTo(cc_messages.TaskStateRunning)).To(BeFalse())
+	})
+
+	It("keeps the wire format of CCTaskState", func() {
+		var state cc_messages.CCTaskState
+		Expect(json.Unmarshal([]byte(`{"task_guid":"t","state":"FAILED","completion_callback":"u"}`), &state)).To(Succeed())
+		Expect(state.State).To(Equal(cc_messages.TaskStateFailed))
+		Expect(state.State.Terminal()).To(BeTrue())
+	})
+
+	DescribeTable("TaskStateFromBBS",
+		func(state models.Task_State, failed bool, expected cc_messages.TaskState) {
+			Expect(cc_messages.TaskStateFromBBS(state, failed)).To(Equal(expected))
+		},
+		Entry("pending", models.Task_Pending, false, cc_messages.TaskStatePending),
+		Entry("running", models.Task_Running, false, cc_messages.TaskStateRunning),
+		Entry("completed", models.Task_Completed, false, cc_messages.TaskStateSucceeded),
+		Entry("completed with failure", models.Task_Completed, true, cc_messages.TaskStateFailed),
+		Entry("resolving with failure", models.Task_Resolving, true, cc_messages.TaskStateFailed),
+	)
+
+	It("rejects the invalid BBS state", func() {
+		_, err := cc_messages.TaskStateForTask(&models.Task{State: models.Task_Invalid})
+		Expect(err).To(Equal(cc_messages.ErrInvalidTaskState{State: "Invalid"}))
+	})
+
+	Describe("ReconcileTasks", func() {
+		task := func(guid string, state models.Task_State) *models.Task {
+			return &models.Task{TaskGuid: guid, State: state}
+		}
+
+		It("works out what to cancel, fail and resend", func() {
+			ccTaskStates := []cc_messages.CCTaskState{
+				{TaskGuid: "running", State: cc_messages.TaskStateRunning},
+				{TaskGuid: "lost", State: cc_messages.TaskStateRunning, CompletionCallbackUrl: "http://cc/lost"},
+				{TaskGuid: "canceling", State: cc_messages.TaskStateCanceling},
+				{TaskGuid: "canceled-already", State: cc_messages.TaskStateCanceling},
+				{TaskGuid: "canceled-and-deleted", State: cc_messages.TaskStateCanceling},
+				{TaskGuid: "completed", State: cc_messages.TaskStateRunning},
+				{TaskGuid: "done", State: cc_messages.TaskStateSucceeded},
+				{TaskGuid: "done-and-deleted", State: cc_messages.TaskStateFailed},
+			}
+			completed := task("completed", models.Task_Completed)
+			bbsTasks := []*models.Task{
+				task("running", models.Task_Running),
+				task("canceling", models.Task_Pending),
+				task("canceled-already", models.Task_Resolving),
+				completed,
+				task("done", models.Task_Completed),
+				task("unknown", models.Task_Running),
+				task("unknown-completed", models.Task_Completed),
+			}
+
+			reconciliation := cc_messages.ReconcileTasks(ccTaskStates, bbsTasks)
+			Expect(reconciliation.Cancel).To(Equal([]string{"canceling", "unknown"}))
+			Expect(reconciliation.FailToCC).To(Equal([]cc_messages.CCTaskState{
+				{TaskGuid: "canceled-and-deleted", State: cc_messages.TaskStateCanceling},
+				{TaskGuid: "lost", State: cc_messages.TaskStateRunning, CompletionCallbackUrl: "http://cc/lost"},
+			}))
+			Expect(reconciliation.ResendCallback).To(Equal([]*models.Task{completed}))
+		})
+
+		It("leaves pending tasks that are not yet in BBS alone", func() {
+			reconciliation := cc_messages.ReconcileTasks(
+				[]cc_messages.CCTaskState{{TaskGuid: "just-created", State: cc_messages.TaskStatePending}},
+				[]*models.Task{},
+			)
+			Expect(reconciliation.Cancel).To(BeEmpty())
+			Expect(reconciliation.FailToCC).To(BeEmpty())
+			Expect(reconciliation.ResendCallback).To(BeEmpty())
+		})
+
+		It("returns empty lists when in sync", func() {
+			reconciliation := cc_messages.ReconcileTasks(
+				[]cc_messages.CCTaskState{{TaskGuid: "t", State: cc_messages.TaskStatePending}},
+				[]*models.Task{task("t", models.Task_Pending)},
+			)
+			Expect(reconciliation.Cancel).To(BeEmpty())
+			Expect(reconciliation.FailToCC).To(BeEmpty())
+			Expect(reconciliation.ResendCallback).To(BeEmpty())
+		})
+	})
+})
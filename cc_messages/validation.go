@@ -0,0 +1,241 @@
+package cc_messages
+
+import (
+	"fmt"
+	"net/url"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+const maxPort = 65535
+
+type ErrInvalidField struct {
+	Field  string
+	Reason string
+}
+
+func (err ErrInvalidField) Error() string {
+	return "Invalid field " + err.Field + ": " + err.Reason
+}
+
+func invalidField(field, reason string) ErrInvalidField {
+	return ErrInvalidField{Field: field, Reason: reason}
+}
+
+func (desireAppMessage DesireAppRequestFromCC) Validate() error {
+	var validationError models.ValidationError
+
+	if desireAppMessage.ProcessGuid == "" {
+		validationError = validationError.Append(invalidField("process_guid", "must not be empty"))
+	}
+
+	switch {
+	case desireAppMessage.DropletUri != "" && desireAppMessage.DockerImageUrl != "":
+		validationError = validationError.Append(invalidField("droplet_uri", "cannot be combined with docker_image"))
+	case desireAppMessage.DropletUri == "" && desireAppMessage.DockerImageUrl == "":
+		validationError = validationError.Append(invalidField("droplet_uri", "one of droplet_uri or docker_image is required"))
+	}
+
+	switch desireAppMessage.Lifecycle {
+	case "", BuildpackLifecycleName, DockerLifecycleName:
+	case CNBLifecycleName:
+		if desireAppMessage.DockerImageUrl != "" {
+			validationError = validationError.Append(invalidField("docker_image", "not allowed for the cnb lifecycle"))
+		}
+	default:
+		validationError = validationError.Append(invalidField("lifecycle", fmt.Sprintf("unknown lifecycle %q", desireAppMessage.Lifecycle)))
+	}
+
+	if desireAppMessage.MemoryMB < 0 {
+		validationError = validationError.Append(invalidField("memory_mb", "must not be negative"))
+	}
+
+	if desireAppMessage.DiskMB < 0 {
+		validationError = validationError.Append(invalidField("disk_mb", "must not be negative"))
+	}
+
+	if desireAppMessage.NumInstances < 0 {
+		validationError = validationError.Append(invalidField("num_instances", "must not be negative"))
+	}
+
+	switch desireAppMessage.HealthCheckType {
+	case UnspecifiedHealthCheckType, PortHealthCheckType, NoneHealthCheckType:
+	case HTTPHealthCheckType:
+		if desireAppMessage.HealthCheckHTTPEndpoint == "" {
+			validationError = validationError.Append(invalidField("health_check_http_endpoint", "required when health_check_type is http"))
+		}
+	default:
+		validationError = validationError.Append(invalidField("health_check_type", fmt.Sprintf("unknown type %q", desireAppMessage.HealthCheckType)))
+	}
+
+	for i, port := range desireAppMessage.Ports {
+		if port == 0 || port > maxPort {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("ports[%d]", i), "must be between 1 and 65535"))
+		}
+	}
+
+	validationError = validationError.Append(validateEnvironment("environment", desireAppMessage.Environment))
+	validationError = validationError.Append(validateEgressRules("egress_rules", desireAppMessage.EgressRules))
+	validationError = validationError.Append(validateVolumeMounts("volume_mounts", desireAppMessage.VolumeMounts))
+	validationError = validationError.Append(desireAppMessage.validateRoutingInfo())
+
+	return validationError.ToError()
+}
+
+func (taskRequest TaskRequestFromCC) Validate() error {
+	var validationError models.ValidationError
+
+	if taskRequest.TaskGuid == "" {
+		validationError = validationError.Append(invalidField("task_guid", "must not be empty"))
+	}
+
+	if taskRequest.Command == "" {
+		validationError = validationError.Append(invalidField("command", "must not be empty"))
+	}
+
+	switch taskRequest.Lifecycle {
+	case BuildpackLifecycleName, CNBLifecycleName:
+		if taskRequest.DropletUri == "" {
+			validationError = validationError.Append(invalidField("droplet_uri", fmt.Sprintf("required for the %s lifecycle", taskRequest.Lifecycle)))
+		}
+		if taskRequest.RootFs == "" {
+			validationError = validationError.Append(invalidField("rootfs", fmt.Sprintf("required for the %s lifecycle", taskRequest.Lifecycle)))
+		}
+		if taskRequest.DockerPath != "" {
+			validationError = validationError.Append(invalidField("docker_path", fmt.Sprintf("not allowed for the %s lifecycle", taskRequest.Lifecycle)))
+		}
+	case DockerLifecycleName:
+		if taskRequest.DockerPath == "" {
+			validationError = validationError.Append(invalidField("docker_path", "required for the docker lifecycle"))
+		}
+		if taskRequest.DropletUri != "" {
+			validationError = validationError.Append(invalidField("droplet_uri", "not allowed for the docker lifecycle"))
+		}
+		if (taskRequest.DockerUser == "") != (taskRequest.DockerPassword == "") {
+			validationError = validationError.Append(invalidField("docker_user", "docker_user and docker_password must be given together"))
+		}
+	default:
+		validationError = validationError.Append(invalidField("lifecycle", fmt.Sprintf("unknown lifecycle %q", taskRequest.Lifecycle)))
+	}
+
+	if taskRequest.MemoryMb < 0 {
+		validationError = validationError.Append(invalidField("memory_mb", "must not be negative"))
+	}
+
+	if taskRequest.DiskMb < 0 {
+		validationError = validationError.Append(invalidField("disk_mb", "must not be negative"))
+	}
+
+	if taskRequest.CompletionCallbackUrl != "" {
+		callbackURL, err := url.Parse(taskRequest.CompletionCallbackUrl)
+		if err != nil || callbackURL.Scheme == "" || callbackURL.Host == "" {
+			validationError = validationError.Append(invalidField("completion_callback", "must be an absolute URL"))
+		}
+	}
+
+	validationError = validationError.Append(validateEnvironment("environment", taskRequest.EnvironmentVariables))
+	validationError = validationError.Append(validateEgressRules("egress_rules", taskRequest.EgressRules))
+	validationError = validationError.Append(validateVolumeMounts("volume_mounts", taskRequest.VolumeMounts))
+
+	return validationError.ToError()
+}
+
+func (desireAppMessage DesireAppRequestFromCC) validateRoutingInfo() models.ValidationError {
+	var validationError models.ValidationError
+
+	httpRoutes, err := desireAppMessage.RoutingInfo.HTTPRoutes()
+	if err != nil {
+		validationError = validationError.Append(invalidField("routing_info."+CC_HTTP_ROUTES, err.Error()))
+	} else {
+		for i, route := range httpRoutes {
+			field := fmt.Sprintf("routing_info.%s[%d]", CC_HTTP_ROUTES, i)
+			if route.Hostname == "" {
+				validationError = validationError.Append(invalidField(field+".hostname", "must not be empty"))
+			}
+			if route.Port > maxPort {
+				validationError = validationError.Append(invalidField(field+".port", "must not exceed 65535"))
+			} else if route.Port != 0 && len(desireAppMessage.Ports) > 0 && !containsPort(desireAppMessage.Ports, route.Port) {
+				validationError = validationError.Append(invalidField(field+".port", fmt.Sprintf("%d is not one of the desired ports", route.Port)))
+			}
+		}
+	}
+
+	tcpRoutes, err := desireAppMessage.RoutingInfo.TCPRoutes()
+	if err != nil {
+		validationError = validationError.Append(invalidField("routing_info."+CC_TCP_ROUTES, err.Error()))
+	} else {
+		for i, route := range tcpRoutes {
+			field := fmt.Sprintf("routing_info.%s[%d]", CC_TCP_ROUTES, i)
+			if route.RouterGroupGuid == "" {
+				validationError = validationError.Append(invalidField(field+".router_group_guid", "must not be empty"))
+			}
+			if route.ExternalPort > maxPort {
+				validationError = validationError.Append(invalidField(field+".external_port", "must not exceed 65535"))
+			}
+			if route.ContainerPort > maxPort {
+				validationError = validationError.Append(invalidField(field+".container_port", "must not exceed 65535"))
+			}
+		}
+	}
+
+	return validationError
+}
+
+func validateEnvironment(field string, env []*models.EnvironmentVariable) models.ValidationError {
+	var validationError models.ValidationError
+	for i, envVar := range env {
+		if envVar == nil || envVar.Name == "" {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("%s[%d].name", field, i), "must not be empty"))
+		}
+	}
+	return validationError
+}
+
+func validateEgressRules(field string, rules []*models.SecurityGroupRule) models.ValidationError {
+	var validationError models.ValidationError
+	for i, rule := range rules {
+		if rule == nil {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("%s[%d]", field, i), "must not be null"))
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			validationError = validationError.Append(invalidField(fmt.Sprintf("%s[%d]", field, i), err.Error()))
+		}
+	}
+	return validationError
+}
+
+func validateVolumeMounts(field string, mounts []*VolumeMount) models.ValidationError {
+	var validationError models.ValidationError
+	for i, mount := range mounts {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if mount == nil {
+			validationError = validationError.Append(invalidField(path, "must not be null"))
+			continue
+		}
+		if mount.Driver == "" {
+			validationError = validationError.Append(invalidField(path+".driver", "must not be empty"))
+		}
+		if mount.ContainerDir == "" {
+			validationError = validationError.Append(invalidField(path+".container_dir", "must not be empty"))
+		}
+		if mount.Mode != "r" && mount.Mode != "rw" {
+			validationError = validationError.Append(invalidField(path+".mode", fmt.Sprintf("must be r or rw, got %q", mount.Mode)))
+		}
+		if mount.DeviceType != "shared" {
+			validationError = validationError.Append(invalidField(path+".device_type", fmt.Sprintf("unsupported device type %q", mount.DeviceType)))
+		} else if mount.Device.VolumeId == "" {
+			validationError = validationError.Append(invalidField(path+".device.volume_id", "must not be empty"))
+		}
+	}
+	return validationError
+}
+
+func containsPort(ports []uint32, port uint32) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
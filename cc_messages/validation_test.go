@@ -0,0 +1,291 @@
+package cc_messages_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/runtimeschema/cc_messages"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validation", func() {
+	invalidFields := func(err error) []string {
+		Expect(err).To(BeAssignableToTypeOf(models.ValidationError{}))
+		fields := []string{}
+		for _, e := range err.(models.ValidationError) {
+			fields = append(fields, e.(cc_messages.ErrInvalidField).Field)
+		}
+		return fields
+	}
+
+	Describe("DesireAppRequestFromCC", func() {
+		var desireAppRequest cc_messages.DesireAppRequestFromCC
+
+		routingInfo := func(key, payload string) cc_messages.CCRouteInfo {
+			raw := json.RawMessage(payload)
+			return cc_messages.CCRouteInfo{key: &raw}
+		}
+
+		BeforeEach(func() {
+			desireAppRequest = cc_messages.DesireAppRequestFromCC{
+				ProcessGuid:  "process-guid",
+				DropletUri:   "http://the-droplet.uri.com",
+				Stack:        "cflinuxfs4",
+				StartCommand: "./run",
+				MemoryMB:     128,
+				DiskMB:       512,
+				NumInstances: 2,
+				Ports:        []uint32{8080},
+				RoutingInfo:  routingInfo(cc_messages.CC_HTTP_ROUTES, `[{"hostname":"route1","port":8080}]`),
+				Environment:  []*models.EnvironmentVariable{{Name: "FOO", Value: "BAR"}},
+				VolumeMounts: []*cc_messages.VolumeMount{
+					{
+						Driver:       "testdriver",
+						ContainerDir: "/data",
+						Mode:         "rw",
+						DeviceType:   "shared",
+						Device:       cc_messages.SharedDevice{VolumeId: "volume-id"},
+					},
+				},
+			}
+		})
+
+		It("accepts a valid request", func() {
+			Expect(desireAppRequest.Validate()).To(Succeed())
+		})
+
+		It("accepts a cnb request and rejects unknown lifecycles", func() {
+			desireAppRequest.Lifecycle = "cnb"
+			Expect(desireAppRequest.Validate()).To(Succeed())
+
+			desireAppRequest.Lifecycle = "kpack"
+			Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf("lifecycle"))
+		})
+
+		It("accepts a valid docker request", func() {
+			desireAppRequest.DropletUri = ""
+			desireAppRequest.DockerImageUrl = "cloudfoundry/diego-docker-app"
+			Expect(desireAppRequest.Validate()).To(Succeed())
+		})
+
+		It("reports every invalid field", func() {
+			desireAppRequest.ProcessGuid = ""
+			desireAppRequest.MemoryMB = -1
+			desireAppRequest.DiskMB = -1
+			desireAppRequest.NumInstances = -1
+
+			Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf(
+				"process_guid",
+				"memory_mb",
+				"disk_mb",
+				"num_instances",
+			))
+		})
+
+		Context("when both a droplet and a docker image are given", func() {
+			BeforeEach(func() {
+				desireAppRequest.DockerImageUrl = "cloudfoundry/diego-docker-app"
+			})
+
+			It("rejects the request", func() {
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf("droplet_uri"))
+			})
+		})
+
+		Context("when neither a droplet nor a docker image is given", func() {
+			BeforeEach(func() {
+				desireAppRequest.DropletUri = ""
+			})
+
+			It("rejects the request", func() {
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf("droplet_uri"))
+			})
+		})
+
+		Context("health checks", func() {
+			It("requires an endpoint for http health checks", func() {
+				desireAppRequest.HealthCheckType = cc_messages.HTTPHealthCheckType
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf("health_check_http_endpoint"))
+
+				desireAppRequest.HealthCheckHTTPEndpoint = "/health"
+				Expect(desireAppRequest.Validate()).To(Succeed())
+			})
+
+			It("rejects unknown health check types", func() {
+				desireAppRequest.HealthCheckType = "carrier-pigeon"
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf("health_check_type"))
+			})
+		})
+
+		Context("ports", func() {
+			It("rejects out of range ports", func() {
+				desireAppRequest.Ports = []uint32{8080, 0, 70000}
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf("ports[1]", "ports[2]"))
+			})
+		})
+
+		Context("routing info", func() {
+			It("reports invalid http routes by index", func() {
+				desireAppRequest.RoutingInfo = routingInfo(cc_messages.CC_HTTP_ROUTES, `[
+					{"hostname":"route1","port":8080},
+					{"hostname":""},
+					{"hostname":"route3","port":9090}
+				]`)
+
+				err := desireAppRequest.Validate()
+				Expect(invalidFields(err)).To(ConsistOf(
+					"routing_info.http_routes[1].hostname",
+					"routing_info.http_routes[2].port",
+				))
+				Expect(err.Error()).To(ContainSubstring("9090 is not one of the desired ports"))
+			})
+
+			It("reports invalid tcp routes by index", func() {
+				desireAppRequest.RoutingInfo = routingInfo(cc_messages.CC_TCP_ROUTES, `[
+					{"router_group_guid":"","external_port":5222,"container_port":8080},
+					{"router_group_guid":"group","external_port":70000,"container_port":8080}
+				]`)
+
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf(
+					"routing_info.tcp_routes[0].router_group_guid",
+					"routing_info.tcp_routes[1].external_port",
+				))
+			})
+
+			It("reports malformed route payloads", func() {
+				desireAppRequest.RoutingInfo = routingInfo(cc_messages.CC_HTTP_ROUTES, `{"hostname":"route1"}`)
+
+				err := desireAppRequest.Validate()
+				Expect(invalidFields(err)).To(ConsistOf("routing_info.http_routes"))
+				Expect(err.Error()).To(ContainSubstring("malformed http_routes in routing info"))
+			})
+
+			It("still validates tcp routes when the http routes are malformed", func() {
+				httpRoutes := json.RawMessage(`{"hostname":"route1"}`)
+				tcpRoutes := json.RawMessage(`[{"router_group_guid":"","external_port":5222,"container_port":8080}]`)
+				desireAppRequest.RoutingInfo = cc_messages.CCRouteInfo{
+					cc_messages.CC_HTTP_ROUTES: &httpRoutes,
+					cc_messages.CC_TCP_ROUTES:  &tcpRoutes,
+				}
+
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf(
+					"routing_info.http_routes",
+					"routing_info.tcp_routes[0].router_group_guid",
+				))
+			})
+
+			It("reports malformed http and tcp routes together", func() {
+				httpRoutes := json.RawMessage(`{"hostname":"route1"}`)
+				tcpRoutes := json.RawMessage(`[{"external_port":"high"}]`)
+				desireAppRequest.RoutingInfo = cc_messages.CCRouteInfo{
+					cc_messages.CC_HTTP_ROUTES: &httpRoutes,
+					cc_messages.CC_TCP_ROUTES:  &tcpRoutes,
+				}
+
+				err := desireAppRequest.Validate()
+				Expect(invalidFields(err)).To(ConsistOf("routing_info.http_routes", "routing_info.tcp_routes"))
+				Expect(err.Error()).To(ContainSubstring("malformed tcp_routes in routing info"))
+			})
+		})
+
+		Context("environment, egress rules and volume mounts", func() {
+			It("reports each invalid entry by index", func() {
+				desireAppRequest.Environment = append(desireAppRequest.Environment, &models.EnvironmentVariable{Value: "nameless"})
+				desireAppRequest.EgressRules = []*models.SecurityGroupRule{
+					{Protocol: "tcp", Destinations: []string{"0.0.0.0/0"}, PortRange: &models.PortRange{Start: 80, End: 443}},
+					{Protocol: "bogus"},
+				}
+				desireAppRequest.VolumeMounts = append(desireAppRequest.VolumeMounts, &cc_messages.VolumeMount{
+					Driver:       "testdriver",
+					ContainerDir: "/data",
+					Mode:         "x",
+					DeviceType:   "shared",
+				})
+
+				Expect(invalidFields(desireAppRequest.Validate())).To(ConsistOf(
+					"environment[1].name",
+					"egress_rules[1]",
+					"volume_mounts[1].mode",
+					"volume_mounts[1].device.volume_id",
+				))
+			})
+		})
+	})
+
+	Describe("TaskRequestFromCC", func() {
+		var taskRequest cc_messages.TaskRequestFromCC
+
+		BeforeEach(func() {
+			taskRequest = cc_messages.TaskRequestFromCC{
+				TaskGuid:              "task-guid",
+				Lifecycle:             "buildpack",
+				DropletUri:            "http://the-droplet.uri.com",
+				RootFs:                "cflinuxfs4",
+				Command:               "rake db:migrate",
+				CompletionCallbackUrl: "http://api.cc.com/internal/v3/tasks/task-guid/completed",
+			}
+		})
+
+		It("accepts a valid buildpack task", func() {
+			Expect(taskRequest.Validate()).To(Succeed())
+		})
+
+		It("accepts a valid cnb task", func() {
+			taskRequest.Lifecycle = "cnb"
+			Expect(taskRequest.Validate()).To(Succeed())
+
+			taskRequest.RootFs = ""
+			Expect(invalidFields(taskRequest.Validate())).To(ConsistOf("rootfs"))
+		})
+
+		It("accepts a valid docker task", func() {
+			taskRequest.Lifecycle = "docker"
+			taskRequest.DropletUri = ""
+			taskRequest.DockerPath = "cloudfoundry/diego-docker-app"
+			Expect(taskRequest.Validate()).To(Succeed())
+		})
+
+		It("reports every invalid field", func() {
+			taskRequest.TaskGuid = ""
+			taskRequest.Command = ""
+			taskRequest.MemoryMb = -1
+			taskRequest.DiskMb = -1
+			taskRequest.CompletionCallbackUrl = "/relative"
+
+			Expect(invalidFields(taskRequest.Validate())).To(ConsistOf(
+				"task_guid",
+				"command",
+				"memory_mb",
+				"disk_mb",
+				"completion_callback",
+			))
+		})
+
+		It("rejects unknown lifecycles", func() {
+			taskRequest.Lifecycle = "kpack"
+			Expect(invalidFields(taskRequest.Validate())).To(ConsistOf("lifecycle"))
+		})
+
+		It("rejects a buildpack task without a droplet or rootfs", func() {
+			taskRequest.DropletUri = ""
+			taskRequest.RootFs = ""
+			taskRequest.DockerPath = "cloudfoundry/diego-docker-app"
+			Expect(invalidFields(taskRequest.Validate())).To(ConsistOf("droplet_uri", "rootfs", "docker_path"))
+		})
+
+		It("rejects a docker task with a droplet or partial credentials", func() {
+			taskRequest.Lifecycle = "docker"
+			taskRequest.DockerPath = "cloudfoundry/diego-docker-app"
+			taskRequest.DockerPassword = "password"
+			Expect(invalidFields(taskRequest.Validate())).To(ConsistOf("droplet_uri", "docker_user"))
+		})
+
+		It("reports invalid environment variables and volume mounts by index", func() {
+			taskRequest.EnvironmentVariables = []*models.EnvironmentVariable{{Name: "FOO"}, {Value: "nameless"}}
+			taskRequest.VolumeMounts = []*cc_messages.VolumeMount{{Mode: "r", DeviceType: "shared", ContainerDir: "/data", Device: cc_messages.SharedDevice{VolumeId: "id"}}}
+			Expect(invalidFields(taskRequest.Validate())).To(ConsistOf("environment[1].name", "volume_mounts[0].driver"))
+		})
+	})
+})